@@ -0,0 +1,700 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/robwestbrook/snippetbox/internal/models"
+	"github.com/robwestbrook/snippetbox/internal/validator"
+)
+
+// envelope defines a type to wrap every JSON response
+// in an object, keyed by what the response holds, such
+// as {"snippet": {...}} or {"error": {...}}.
+type envelope map[string]any
+
+// apiError defines a type to hold the details of an
+// API error, sent as {"error": {...}}.
+// Contains:
+//	1. Status - the HTTP status code of the response
+//	2. Message - a description of what went wrong
+//	3. Fields - validation errors for specific fields,
+//							keyed by the field name
+type apiError struct {
+	Status	int								`json:"status"`
+	Message	string						`json:"message"`
+	Fields	map[string]string	`json:"fields,omitempty"`
+}
+
+// maxJSONBytes limits the size of a JSON request body.
+const maxJSONBytes = 1 << 20
+
+/*
+	writeJSON function encodes data as JSON and sends it
+	with the given HTTP status code.
+*/
+func (app *application) writeJSON(w http.ResponseWriter, status int, data envelope) {
+	js, err := json.MarshalIndent(data, "", "\t")
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	// Add a newline to make the response easier to read
+	// in a terminal
+	js = append(js, '\n')
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(js)
+}
+
+/*
+	readJSON function decodes the JSON request body into
+	dst. The body must hold a single JSON object with no
+	fields dst doesn't have. The errors returned describe
+	the problem well enough to send back to the client.
+*/
+func (app *application) readJSON(w http.ResponseWriter, r *http.Request, dst any) error {
+	// Stop reading after maxJSONBytes
+	r.Body = http.MaxBytesReader(w, r.Body, maxJSONBytes)
+
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+
+	err := dec.Decode(dst)
+	if err != nil {
+		var syntaxError *json.SyntaxError
+		var unmarshalTypeError *json.UnmarshalTypeError
+		var maxBytesError *http.MaxBytesError
+
+		switch {
+		case errors.As(err, &syntaxError):
+			return fmt.Errorf("body contains badly-formed JSON (at character %d)", syntaxError.Offset)
+		case errors.Is(err, io.ErrUnexpectedEOF):
+			return errors.New("body contains badly-formed JSON")
+		case errors.As(err, &unmarshalTypeError):
+			return fmt.Errorf("body contains the wrong type for the %q field", unmarshalTypeError.Field)
+		case errors.Is(err, io.EOF):
+			return errors.New("body must not be empty")
+		case strings.HasPrefix(err.Error(), "json: unknown field "):
+			return fmt.Errorf("body contains unknown field %s", strings.TrimPrefix(err.Error(), "json: unknown field "))
+		case errors.As(err, &maxBytesError):
+			return fmt.Errorf("body must not be larger than %d bytes", maxBytesError.Limit)
+		default:
+			return err
+		}
+	}
+
+	// Anything after the first JSON value is an error
+	err = dec.Decode(&struct{}{})
+	if err != io.EOF {
+		return errors.New("body must only contain a single JSON value")
+	}
+
+	return nil
+}
+
+/*
+	apiErrorResponse function sends an error in the
+	{"error": {...}} envelope every API error uses.
+*/
+func (app *application) apiErrorResponse(w http.ResponseWriter, status int, message string, fields map[string]string) {
+	app.writeJSON(w, status, envelope{
+		"error": apiError{Status: status, Message: message, Fields: fields},
+	})
+}
+
+/*
+	apiServerError function writes an error message and
+	stack trace to the errorLog, like serverError, then
+	sends a generic 500 Internal Server Error as JSON.
+*/
+func (app *application) apiServerError(w http.ResponseWriter, err error) {
+	trace := fmt.Sprintf("%s\n%s", err.Error(), debug.Stack())
+	app.errorLog.Output(2, trace)
+
+	app.apiErrorResponse(w, http.StatusInternalServerError, "the server encountered a problem and could not process the request", nil)
+}
+
+/*
+	apiNotFound function sends a 404 Not Found as JSON.
+*/
+func (app *application) apiNotFound(w http.ResponseWriter) {
+	app.apiErrorResponse(w, http.StatusNotFound, "the requested resource could not be found", nil)
+}
+
+/*
+	apiMethodNotAllowed function sends a 405 Method Not
+	Allowed as JSON.
+*/
+func (app *application) apiMethodNotAllowed(w http.ResponseWriter, r *http.Request) {
+	app.apiErrorResponse(w, http.StatusMethodNotAllowed, fmt.Sprintf("the %s method is not supported for this resource", r.Method), nil)
+}
+
+/*
+	apiBadRequest function sends a 400 Bad Request as
+	JSON, with err as the message.
+*/
+func (app *application) apiBadRequest(w http.ResponseWriter, err error) {
+	app.apiErrorResponse(w, http.StatusBadRequest, err.Error(), nil)
+}
+
+/*
+	apiFailedValidation function sends a 422
+	Unprocessable Entity as JSON, with the validator's
+	field errors.
+*/
+func (app *application) apiFailedValidation(w http.ResponseWriter, fieldErrors map[string]string) {
+	app.apiErrorResponse(w, http.StatusUnprocessableEntity, "the request failed validation", fieldErrors)
+}
+
+/*
+	apiAuthenticationRequired function sends a 401
+	Unauthorized as JSON, telling the client how to
+	authenticate.
+*/
+func (app *application) apiAuthenticationRequired(w http.ResponseWriter, message string) {
+	w.Header().Set("WWW-Authenticate", `Bearer realm="snippetbox"`)
+	app.apiErrorResponse(w, http.StatusUnauthorized, message, nil)
+}
+
+/*
+	apiForbidden function sends a 403 Forbidden as JSON.
+*/
+func (app *application) apiForbidden(w http.ResponseWriter) {
+	app.apiErrorResponse(w, http.StatusForbidden, "you do not have permission to change this snippet", nil)
+}
+
+/*
+	apiSnippetFromURL function gets the snippet with the
+	slug in the URL, like snippetFromURL, but sends any
+	error as JSON. Legacy snippets asked for by ID are
+	sent rather than redirected, so API clients don't
+	have to follow redirects.
+*/
+func (app *application) apiSnippetFromURL(w http.ResponseWriter, r *http.Request) (*models.Snippet, bool) {
+	snippet, err := app.lookupSnippet(r)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.apiNotFound(w)
+		} else {
+			app.apiServerError(w, err)
+		}
+		return nil, false
+	}
+
+	return snippet, true
+}
+
+/*
+	apiOwnedSnippet function gets the snippet with the ID
+	in the URL, like ownedSnippet, checking it belongs to
+	the authenticated user, but sends any error as JSON.
+*/
+func (app *application) apiOwnedSnippet(w http.ResponseWriter, r *http.Request) (*models.Snippet, bool) {
+	snippet, ok := app.apiSnippetFromURL(w, r)
+	if !ok {
+		return nil, false
+	}
+
+	if !snippet.OwnedBy(app.authenticatedUserID(r)) {
+		app.apiForbidden(w)
+		return nil, false
+	}
+
+	return snippet, true
+}
+
+// snippetCreateInput defines a type to hold the JSON
+// body of a request creating a snippet.
+type snippetCreateInput struct {
+	Title			string		`json:"title"`
+	Content		string		`json:"content"`
+	Language	string		`json:"language"`
+	Visibility	string	`json:"visibility"`
+	Burn			bool			`json:"burn_after_reading"`
+	Encrypted	bool			`json:"encrypted"`
+	Password	string		`json:"password"`
+	Tags			[]string	`json:"tags"`
+	Expires		expiresInput	`json:"expires"`
+	ExpiresAt	string		`json:"expires_at"`
+}
+
+// snippetUpdateInput defines a type to hold the JSON
+// body of a request updating a snippet. The expiry
+// date is only changed if expires or expires_at is
+// given.
+type snippetUpdateInput struct {
+	Title			string		`json:"title"`
+	Content		string		`json:"content"`
+	Language	string		`json:"language"`
+	Visibility	string	`json:"visibility"`
+	Tags			[]string	`json:"tags"`
+	Expires		expiresInput	`json:"expires"`
+	ExpiresAt	string		`json:"expires_at"`
+}
+
+/*
+	apiSnippetList function handles
+	GET /api/v1/snippets, sending a page of unexpired
+	snippets. The query string can hold:
+		1. sort - created (the default), expires or title
+		2. tag - only list snippets with this tag
+		3. limit - the number of snippets, from 1 to 100
+		4. after, before - the next and prev cursors of
+											 another page
+*/
+func (app *application) apiSnippetList(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	opts := models.ListOptions{
+		Sort:   query.Get("sort"),
+		Tag:    query.Get("tag"),
+		After:  query.Get("after"),
+		Before: query.Get("before"),
+		Limit:  app.config.pageSize,
+	}
+
+	// Check the query string, in the same way as a form
+	var v validator.Validator
+
+	if s := query.Get("limit"); s != "" {
+		limit, err := strconv.Atoi(s)
+		v.CheckField(
+			err == nil && limit >= 1 && limit <= 100,
+			"limit",
+			"This field must be a number from 1 to 100")
+		opts.Limit = limit
+	}
+
+	if opts.Tag != "" {
+		v.CheckField(
+			validator.Matches(opts.Tag, validator.TagRX),
+			"tag",
+			"This field must be a valid tag")
+	}
+
+	if !v.Valid() {
+		app.apiFailedValidation(w, v.FieldErrors)
+		return
+	}
+
+	page, err := app.snippets.List(opts)
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrInvalidSort):
+			v.AddFieldError("sort", "This field must equal created, expires or title")
+			app.apiFailedValidation(w, v.FieldErrors)
+		case errors.Is(err, models.ErrInvalidCursor):
+			app.apiBadRequest(w, errors.New("the after or before cursor is invalid"))
+		default:
+			app.apiServerError(w, err)
+		}
+		return
+	}
+
+	app.writeJSON(w, http.StatusOK, envelope{
+		"snippets": page.Snippets,
+		"page":     page,
+	})
+}
+
+/*
+	apiSnippetGet function handles
+	GET /api/v1/snippets/:slug, sending a single snippet.
+	Anyone else asking for a snippet with a password
+	must send the password in the X-Snippet-Password
+	header. GET requests are made by link previews and
+	crawlers as well as people, so burn after reading
+	snippets are only sent without their content, unless
+	they are sent to their owner. Reading one, which
+	deletes it, is done with apiSnippetReveal.
+*/
+func (app *application) apiSnippetGet(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.apiSnippetFromURL(w, r)
+	if !ok {
+		return
+	}
+
+	if !app.apiUnlock(w, r, snippet) {
+		return
+	}
+
+	if snippet.Burn && !snippet.OwnedBy(app.authenticatedUserID(r)) {
+		snippet.Content = ""
+	}
+
+	app.writeJSON(w, http.StatusOK, envelope{"snippet": snippet})
+}
+
+/*
+	apiSnippetReveal function handles
+	POST /api/v1/snippets/:slug/reveal, sending a single
+	snippet with its content, like the reveal step of
+	the web interface. Burn after reading snippets are
+	deleted as they are sent, unless they are sent to
+	their owner.
+*/
+func (app *application) apiSnippetReveal(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.apiSnippetFromURL(w, r)
+	if !ok {
+		return
+	}
+
+	if !app.apiUnlock(w, r, snippet) {
+		return
+	}
+
+	if snippet.Burn && !snippet.OwnedBy(app.authenticatedUserID(r)) {
+		var err error
+
+		snippet, err = app.snippets.Burn(snippet.Slug, app.authenticatedUserID(r))
+		if err != nil {
+			if errors.Is(err, models.ErrNoRecord) {
+				app.apiNotFound(w)
+			} else {
+				app.apiServerError(w, err)
+			}
+			return
+		}
+	}
+
+	app.writeJSON(w, http.StatusOK, envelope{"snippet": snippet})
+}
+
+/*
+	apiUnlock function checks the password in the
+	X-Snippet-Password header for a snippet with a
+	password, unless the snippet belongs to the user.
+	If the password isn't right an error response is
+	sent and false is returned. Like the unlock form,
+	clients which send too many wrong passwords are
+	made to wait before they can try again.
+*/
+func (app *application) apiUnlock(w http.ResponseWriter, r *http.Request, snippet *models.Snippet) bool {
+	if !snippet.Protected || snippet.OwnedBy(app.authenticatedUserID(r)) {
+		return true
+	}
+
+	ok, wait := app.unlocks.allow(clientIP(r))
+	if !ok {
+		seconds := int(wait.Seconds()) + 1
+		w.Header().Set("Retry-After", strconv.Itoa(seconds))
+		app.apiErrorResponse(w, http.StatusTooManyRequests, fmt.Sprintf("too many incorrect passwords, try again in %d seconds", seconds), nil)
+		return false
+	}
+
+	err := app.snippets.Unlock(snippet.ID, r.Header.Get("X-Snippet-Password"))
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrInvalidCredentials):
+			app.unlocks.fail(clientIP(r))
+			app.apiErrorResponse(w, http.StatusForbidden, "this snippet needs its password in the X-Snippet-Password header", nil)
+		case errors.Is(err, models.ErrNoRecord):
+			app.apiNotFound(w)
+		default:
+			app.apiServerError(w, err)
+		}
+		return false
+	}
+
+	return true
+}
+
+/*
+	apiSnippetCreate function handles
+	POST /api/v1/snippets, creating a snippet owned by
+	the authenticated user. The snippet is validated in
+	the same way as the create snippet form. An empty
+	language is detected from the content, visibility
+	defaults to public and expires defaults to the
+	server's default expiry.
+*/
+func (app *application) apiSnippetCreate(w http.ResponseWriter, r *http.Request) {
+	var input snippetCreateInput
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.apiBadRequest(w, err)
+		return
+	}
+
+	if input.Language == "" {
+		input.Language = autoDetect
+	}
+	if input.Visibility == "" {
+		input.Visibility = models.VisibilityPublic
+	}
+	if input.Expires == "" {
+		input.Expires = expiresInput(app.config.expiry.def)
+	}
+
+	// Run the same checks as the create snippet form
+	form := snippetCreateForm{
+		Title:      input.Title,
+		Content:    input.Content,
+		Tags:       strings.Join(input.Tags, ","),
+		Language:   input.Language,
+		Visibility: input.Visibility,
+		Burn:       input.Burn,
+		Encrypted:  input.Encrypted,
+		Password:   input.Password,
+		Expires:    string(input.Expires),
+		ExpiresAt:  input.ExpiresAt,
+	}
+	form.checkTitleAndContent()
+	form.checkEncrypted()
+	language := form.checkLanguage()
+	form.checkVisibility()
+	form.checkPassword()
+	tags := form.checkTags()
+	expires := form.checkExpires(app.config.expiry, false)
+
+	if !form.Valid() {
+		app.apiFailedValidation(w, form.FieldErrors)
+		return
+	}
+
+	slug, err := app.snippets.Insert(form.Title, form.Content, language, form.Visibility, form.Burn, form.Encrypted, form.Password, tags, expires, app.authenticatedUserID(r))
+	if err != nil {
+		app.apiServerError(w, err)
+		return
+	}
+
+	// Send back the snippet as it was stored
+	snippet, err := app.snippets.Get(slug, app.authenticatedUserID(r))
+	if err != nil {
+		app.apiServerError(w, err)
+		return
+	}
+
+	w.Header().Set("Location", "/api/v1/snippets/"+slug)
+	app.writeJSON(w, http.StatusCreated, envelope{"snippet": snippet})
+}
+
+/*
+	apiSnippetUpdate function handles
+	PUT /api/v1/snippets/:slug, replacing the title,
+	content, language, visibility and tags of a snippet
+	owned by the authenticated user. An empty visibility
+	leaves it as it is. As with the edit snippet form,
+	the expiry date is only changed if expires or
+	expires_at is given, counting from now.
+*/
+func (app *application) apiSnippetUpdate(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.apiOwnedSnippet(w, r)
+	if !ok {
+		return
+	}
+
+	var input snippetUpdateInput
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.apiBadRequest(w, err)
+		return
+	}
+
+	if input.Language == "" {
+		input.Language = autoDetect
+	}
+	if input.Visibility == "" {
+		input.Visibility = snippet.Visibility
+	}
+
+	// Run the same checks as the edit snippet form
+	form := snippetCreateForm{
+		Title:      input.Title,
+		Content:    input.Content,
+		Tags:       strings.Join(input.Tags, ","),
+		Language:   input.Language,
+		Visibility: input.Visibility,
+		Burn:       snippet.Burn,
+		Encrypted:  snippet.Encrypted,
+		Expires:    string(input.Expires),
+		ExpiresAt:  input.ExpiresAt,
+	}
+	form.checkTitleAndContent()
+	form.checkEncrypted()
+	language := form.checkLanguage()
+	form.checkVisibility()
+	tags := form.checkTags()
+	expires := form.checkExpires(app.config.expiry, true)
+
+	if !form.Valid() {
+		app.apiFailedValidation(w, form.FieldErrors)
+		return
+	}
+
+	err = app.snippets.Update(snippet.ID, form.Title, form.Content, language, form.Visibility, tags, expires, app.authenticatedUserID(r))
+	if err != nil {
+		app.apiServerError(w, err)
+		return
+	}
+
+	// Send back the snippet as it was stored
+	snippet, err = app.snippets.Get(snippet.Slug, app.authenticatedUserID(r))
+	if err != nil {
+		app.apiServerError(w, err)
+		return
+	}
+
+	app.writeJSON(w, http.StatusOK, envelope{"snippet": snippet})
+}
+
+/*
+	apiSnippetDelete function handles
+	DELETE /api/v1/snippets/:slug, moving a snippet owned
+	by the authenticated user to their trash.
+*/
+func (app *application) apiSnippetDelete(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.apiOwnedSnippet(w, r)
+	if !ok {
+		return
+	}
+
+	err := app.snippets.Delete(snippet.ID)
+	if err != nil {
+		app.apiServerError(w, err)
+		return
+	}
+
+	app.writeJSON(w, http.StatusOK, envelope{"message": "snippet moved to the trash"})
+}
+
+/*
+	apiUser function handles GET /api/v1/user, sending
+	the user the API token belongs to.
+*/
+func (app *application) apiUser(w http.ResponseWriter, r *http.Request) {
+	user, err := app.users.Get(app.authenticatedUserID(r))
+	if err != nil {
+		app.apiServerError(w, err)
+		return
+	}
+
+	app.writeJSON(w, http.StatusOK, envelope{"user": user})
+}
+
+/*
+	apiPaste function handles POST /api/v1/paste, which
+	creates a snippet from the raw request body, so the
+	output of a command can be piped straight in:
+
+		cmd | curl --data-binary @- -H "Authorization: Bearer ..." \
+			https://localhost:4000/api/v1/paste?title=Output
+
+	The title, language, visibility, tags and expires
+	options can be given in the query string, or in
+	X-Snippet-Title, X-Snippet-Language,
+	X-Snippet-Visibility, X-Snippet-Tags and
+	X-Snippet-Expires headers. Tags are separated by
+	commas. The title defaults to the first line of the
+	body. The URL of the new snippet is sent back as
+	plain text, but errors are sent as JSON like the rest
+	of the API.
+*/
+func (app *application) apiPaste(w http.ResponseWriter, r *http.Request) {
+	// Read the whole body, up to the paste limit
+	r.Body = http.MaxBytesReader(w, r.Body, app.config.pasteLimit)
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		var maxBytesError *http.MaxBytesError
+		if errors.As(err, &maxBytesError) {
+			app.apiErrorResponse(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("body must not be larger than %d bytes", maxBytesError.Limit), nil)
+		} else {
+			app.apiBadRequest(w, err)
+		}
+		return
+	}
+
+	form := snippetCreateForm{
+		Title:    pasteOption(r, "title"),
+		Content:  string(body),
+		Language:   pasteOption(r, "language"),
+		Visibility: pasteOption(r, "visibility"),
+		Tags:       pasteOption(r, "tags"),
+		Expires:    pasteOption(r, "expires"),
+	}
+
+	if form.Title == "" {
+		form.Title = pasteTitle(form.Content)
+	}
+	if form.Language == "" {
+		form.Language = autoDetect
+	}
+	if form.Visibility == "" {
+		form.Visibility = models.VisibilityPublic
+	}
+	if form.Expires == "" {
+		form.Expires = app.config.expiry.def
+	}
+
+	// Run the same checks as the create snippet form.
+	// The body could be anything, so check it's text.
+	form.checkTitleAndContent()
+	form.CheckField(utf8.Valid(body), "content", "This field must be UTF-8 text")
+	language := form.checkLanguage()
+	form.checkVisibility()
+	tags := form.checkTags()
+	expires := form.checkExpires(app.config.expiry, false)
+
+	if !form.Valid() {
+		app.apiFailedValidation(w, form.FieldErrors)
+		return
+	}
+
+	slug, err := app.snippets.Insert(form.Title, form.Content, language, form.Visibility, false, false, "", tags, expires, app.authenticatedUserID(r))
+	if err != nil {
+		app.apiServerError(w, err)
+		return
+	}
+
+	url := app.absoluteURL(r, "/snippet/view/"+slug)
+
+	w.Header().Set("Location", url)
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(http.StatusCreated)
+	fmt.Fprintln(w, url)
+}
+
+/*
+	pasteOption function returns a raw paste option from
+	the query string, or from its X-Snippet- header if
+	it isn't in the query string.
+*/
+func pasteOption(r *http.Request, name string) string {
+	if v := r.URL.Query().Get(name); v != "" {
+		return v
+	}
+	return r.Header.Get("X-Snippet-" + name)
+}
+
+/*
+	pasteTitle function returns a title for a paste
+	without one: its first line which isn't blank,
+	shortened to fit.
+*/
+func pasteTitle(content string) string {
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		if utf8.RuneCountInString(line) > maxTitleChars {
+			line = string([]rune(line)[:maxTitleChars-1]) + "…"
+		}
+		return line
+	}
+	return ""
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/robwestbrook/snippetbox/internal/models"
+)
+
+func TestAPIAuthentication(t *testing.T) {
+	app := newTestApplication(t)
+	owner := newTestUser(t, app, "owner")
+
+	read, err := app.users.InsertToken(owner, "Read", models.ScopeRead)
+	if err != nil {
+		t.Fatal(err)
+	}
+	write, err := app.users.InsertToken(owner, "Write", models.ScopeWrite)
+	if err != nil {
+		t.Fatal(err)
+	}
+	revoked, err := app.users.InsertToken(owner, "Revoked", models.ScopeWrite)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tokens, err := app.users.Tokens(owner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Tokens are listed newest first
+	err = app.users.RevokeToken(tokens[0].ID, owner)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ts := newTestServer(t, app.routes())
+
+	// An empty authorization sends no Authorization
+	// header at all
+	tests := []struct {
+		name					string
+		method				string
+		path					string
+		authorization	string
+		wantCode			int
+	}{
+		{"No token", http.MethodPost, "/api/v1/snippets", "", http.StatusUnauthorized},
+		{"Not a bearer token", http.MethodPost, "/api/v1/snippets", "Basic " + write, http.StatusUnauthorized},
+		{"Bearer without a token", http.MethodPost, "/api/v1/snippets", "Bearer", http.StatusUnauthorized},
+		{"Unknown token", http.MethodPost, "/api/v1/snippets", "Bearer sbx_unknown", http.StatusUnauthorized},
+		{"Revoked token", http.MethodPost, "/api/v1/snippets", "Bearer " + revoked, http.StatusUnauthorized},
+		{"Read token writing", http.MethodPost, "/api/v1/snippets", "Bearer " + read, http.StatusForbidden},
+		{"Write token writing", http.MethodPost, "/api/v1/snippets", "Bearer " + write, http.StatusCreated},
+		{"Read token reading", http.MethodGet, "/api/v1/user", "Bearer " + read, http.StatusOK},
+		{"Write token reading", http.MethodGet, "/api/v1/user", "Bearer " + write, http.StatusOK},
+		{"Unknown token on a public endpoint", http.MethodGet, "/api/v1/snippets", "Bearer sbx_unknown", http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := http.Header{"Content-Type": {"application/json"}}
+			if tt.authorization != "" {
+				header.Set("Authorization", tt.authorization)
+			}
+			body := strings.NewReader(`{"title": "An API snippet", "content": "Its content", "language": "text"}`)
+
+			code, _, resp := ts.do(t, tt.method, tt.path, body, header)
+			if code != tt.wantCode {
+				t.Errorf("got status %d, want %d: %s", code, tt.wantCode, resp)
+			}
+		})
+	}
+}
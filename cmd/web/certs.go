@@ -0,0 +1,335 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// certValidity is how long generated certificates are
+// valid for.
+const certValidity = 365 * 24 * time.Hour
+
+/*
+	generateCert function generates a self-signed ECDSA
+	certificate for the given host names and IP
+	addresses, returning the certificate and its private
+	key PEM encoded, as tls.X509KeyPair() takes them.
+	Browsers won't trust it, so it is only for
+	development.
+*/
+func generateCert(hosts []string, now time.Time) (certPEM []byte, keyPEM []byte, err error) {
+	if len(hosts) == 0 {
+		return nil, nil, errors.New("a certificate needs at least one host name")
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Serial numbers must be unique for each certificate
+	// from an issuer, so pick a random 128 bit one
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	template := x509.Certificate{
+		SerialNumber:	serial,
+		Subject:			pkix.Name{Organization: []string{"Snippetbox development"}, CommonName: hosts[0]},
+		// Allow for clocks which are a little behind
+		NotBefore:		now.Add(-time.Hour),
+		NotAfter:			now.Add(certValidity),
+		KeyUsage:			x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:	[]x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid:	true,
+		IsCA:					true,
+	}
+
+	for _, h := range hosts {
+		if ip := net.ParseIP(h); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else {
+			template.DNSNames = append(template.DNSNames, h)
+		}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	certPEM = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM = pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER})
+	return certPEM, keyPEM, nil
+}
+
+/*
+	newTLSConfig function returns the TLS settings for
+	the server. The certificate is generated in memory
+	for -dev-tls, and otherwise served from the
+	-tls-cert and -tls-key files, reloading them when
+	they change. There is no certificate when serving
+	plain HTTP. Only elliptic curves with assembly
+	implementations are used.
+*/
+func newTLSConfig(cfg *config, errorLog *log.Logger) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		CurvePreferences: []tls.CurveID{tls.X25519, tls.CurveP256},
+	}
+
+	switch {
+	case cfg.plainHTTP:
+		return tlsConfig, nil
+
+	case cfg.devTLS:
+		certPEM, keyPEM, err := generateCert(cfg.certHosts, time.Now())
+		if err != nil {
+			return nil, fmt.Errorf("generating TLS certificate: %w", err)
+		}
+		cert, err := tls.X509KeyPair(certPEM, keyPEM)
+		if err != nil {
+			return nil, fmt.Errorf("generating TLS certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+
+	default:
+		cr, err := newCertReloader(cfg.tls.certFile, cfg.tls.keyFile, errorLog)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.GetCertificate = cr.GetCertificate
+	}
+
+	return tlsConfig, nil
+}
+
+/*
+	gencert function runs the "gencert" subcommand, which
+	generates a self-signed certificate for the hosts in
+	-cert-hosts and writes it to the -tls-cert and
+	-tls-key files. Existing files are only replaced
+	with -force, for example
+	"web -cert-hosts localhost,dev.test gencert -force".
+	A running server picks up the new certificate
+	without a restart.
+*/
+func gencert(cfg *config, out io.Writer, args []string) error {
+	fs := flag.NewFlagSet("gencert", flag.ContinueOnError)
+	fs.SetOutput(out)
+	force := fs.Bool("force", false, "Replace an existing certificate and key")
+
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return fmt.Errorf("usage: gencert [-force]")
+	}
+
+	if !*force {
+		for _, path := range []string{cfg.tls.certFile, cfg.tls.keyFile} {
+			if _, err := os.Stat(path); err == nil {
+				return fmt.Errorf("gencert: %s already exists, use -force to replace it", path)
+			}
+		}
+	}
+
+	certPEM, keyPEM, err := generateCert(cfg.certHosts, time.Now())
+	if err != nil {
+		return err
+	}
+
+	// Write the key first, so a running server which
+	// sees the new certificate finds its key too. Only
+	// the owner may read the key.
+	err = writeFileAtomic(cfg.tls.keyFile, keyPEM, 0600)
+	if err != nil {
+		return err
+	}
+	err = writeFileAtomic(cfg.tls.certFile, certPEM, 0644)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "Wrote a certificate for %s to %s and its key to %s\n", strings.Join(cfg.certHosts, ", "), cfg.tls.certFile, cfg.tls.keyFile)
+	return nil
+}
+
+/*
+	writeFileAtomic function writes data to a temporary
+	file next to path, then renames it over path, so
+	nothing ever reads a half written file. The
+	directory is created if need be.
+*/
+func writeFileAtomic(path string, data []byte, perm fs.FileMode) error {
+	dir := filepath.Dir(path)
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.CreateTemp(dir, "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	_, err = f.Write(data)
+	if err == nil {
+		err = f.Chmod(perm)
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(f.Name(), path)
+}
+
+// certReloader defines a type which serves the TLS
+// certificate from a pair of files, loading it again
+// when either file changes, so a certificate can be
+// renewed without restarting the server.
+// Contains:
+//	1. certFile, keyFile - the files to load
+//	2. errorLog - logs certificates which fail to load
+//	3. mu - guards the fields below
+//	4. cert - the certificate being served
+//	5. certStat, keyStat - the files' sizes and
+//		 modification times when cert was loaded
+type certReloader struct {
+	certFile	string
+	keyFile		string
+	errorLog	*log.Logger
+	mu				sync.Mutex
+	cert			*tls.Certificate
+	certStat	fileStamp
+	keyStat		fileStamp
+}
+
+// fileStamp defines a type to hold what is checked to
+// tell if a file has changed.
+type fileStamp struct {
+	size		int64
+	modTime	time.Time
+}
+
+/*
+	newCertReloader function loads the certificate in
+	certFile and keyFile, failing if it can't be, so a
+	missing certificate is reported on startup.
+*/
+func newCertReloader(certFile, keyFile string, errorLog *log.Logger) (*certReloader, error) {
+	cr := &certReloader{certFile: certFile, keyFile: keyFile, errorLog: errorLog}
+
+	err := cr.reload()
+	if err != nil {
+		return nil, fmt.Errorf("loading TLS certificate (create one with the gencert subcommand, or use -dev-tls): %w", err)
+	}
+	return cr, nil
+}
+
+/*
+	GetCertificate function returns the certificate for
+	each TLS handshake, for tls.Config. If the files have
+	changed it is loaded again first. A certificate which
+	fails to load, perhaps because only one of the files
+	has been replaced so far, is logged and the previous
+	one is served until the next handshake tries again.
+*/
+func (cr *certReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	cr.mu.Lock()
+	defer cr.mu.Unlock()
+
+	certStat, certErr := stampFile(cr.certFile)
+	keyStat, keyErr := stampFile(cr.keyFile)
+	changed := certErr == nil && keyErr == nil && (certStat != cr.certStat || keyStat != cr.keyStat)
+
+	if changed {
+		if err := cr.reloadLocked(); err != nil {
+			cr.errorLog.Printf("reloading TLS certificate: %s", err)
+
+			// Don't try again until the files change again,
+			// rather than on every handshake
+			cr.certStat = certStat
+			cr.keyStat = keyStat
+		}
+	}
+
+	return cr.cert, nil
+}
+
+/*
+	reload function loads the certificate from the
+	files.
+*/
+func (cr *certReloader) reload() error {
+	cr.mu.Lock()
+	defer cr.mu.Unlock()
+	return cr.reloadLocked()
+}
+
+/*
+	reloadLocked function loads the certificate from the
+	files, with cr.mu held. The files are stamped before
+	they are read, so a change made while reading is
+	seen next time.
+*/
+func (cr *certReloader) reloadLocked() error {
+	certStat, err := stampFile(cr.certFile)
+	if err != nil {
+		return err
+	}
+	keyStat, err := stampFile(cr.keyFile)
+	if err != nil {
+		return err
+	}
+
+	cert, err := tls.LoadX509KeyPair(cr.certFile, cr.keyFile)
+	if err != nil {
+		return err
+	}
+
+	cr.cert = &cert
+	cr.certStat = certStat
+	cr.keyStat = keyStat
+	return nil
+}
+
+/*
+	stampFile function returns the size and modification
+	time of the file at path.
+*/
+func stampFile(path string) (fileStamp, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fileStamp{}, err
+	}
+	return fileStamp{size: info.Size(), modTime: info.ModTime()}, nil
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bytes"
+	"crypto/x509"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGenerateCert(t *testing.T) {
+	now := time.Now()
+
+	certPEM, keyPEM, err := generateCert([]string{"localhost", "127.0.0.1", "dev.test"}, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	writeFile(t, certFile, certPEM)
+	writeFile(t, keyFile, keyPEM)
+
+	cr, err := newCertReloader(certFile, keyFile, log.New(io.Discard, "", 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	leaf := servedLeaf(t, cr)
+	if err := leaf.VerifyHostname("dev.test"); err != nil {
+		t.Error(err)
+	}
+	if err := leaf.VerifyHostname("127.0.0.1"); err != nil {
+		t.Error(err)
+	}
+	if err := leaf.VerifyHostname("example.com"); err == nil {
+		t.Error("got a certificate for example.com, want one only for the hosts given")
+	}
+	if got := leaf.NotAfter.Sub(now).Round(time.Hour); got != certValidity {
+		t.Errorf("got a certificate valid for %s, want %s", got, certValidity)
+	}
+}
+
+func TestCertReloader(t *testing.T) {
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+
+	var logs bytes.Buffer
+	_, err := newCertReloader(certFile, keyFile, log.New(&logs, "", 0))
+	if err == nil {
+		t.Fatal("got a reloader for missing files, want an error")
+	}
+
+	certPEM, keyPEM, err := generateCert([]string{"old.test"}, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, certFile, certPEM)
+	writeFile(t, keyFile, keyPEM)
+
+	cr, err := newCertReloader(certFile, keyFile, log.New(&logs, "", 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Replace the key on its own. It doesn't match the
+	// certificate, so the old certificate is still served.
+	certPEM, keyPEM, err = generateCert([]string{"new.test"}, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	later := time.Now().Add(time.Minute)
+	writeFile(t, keyFile, keyPEM)
+	os.Chtimes(keyFile, later, later)
+
+	if got := servedLeaf(t, cr).Subject.CommonName; got != "old.test" {
+		t.Errorf("got %s with only the key replaced, want old.test", got)
+	}
+	if !strings.Contains(logs.String(), "reloading TLS certificate") {
+		t.Errorf("got logs %q, want the failed reload logged", logs.String())
+	}
+
+	// Once the certificate is replaced too, the new one is
+	// served
+	writeFile(t, certFile, certPEM)
+	os.Chtimes(certFile, later, later)
+
+	if got := servedLeaf(t, cr).Subject.CommonName; got != "new.test" {
+		t.Errorf("got %s after the rotation, want new.test", got)
+	}
+}
+
+func writeFile(t *testing.T, path string, data []byte) {
+	t.Helper()
+
+	err := os.WriteFile(path, data, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func servedLeaf(t *testing.T, cr *certReloader) *x509.Certificate {
+	t.Helper()
+
+	cert, err := cr.GetCertificate(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return leaf
+}
@@ -0,0 +1,438 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Define a config struct to hold the configuration
+// settings for the application. Every setting is a
+// command line flag, and can also be set by an
+// environment variable or in a config file, see
+// loadConfig().
+//
+// Settings available:
+//	1. addr - HTTP network address
+//	2. dsn - SQLite data source name
+//	3. sweep.interval - how often expired data is purged
+//	4. sweep.batch - how many rows are purged at a time
+//	5. autoMigrate - apply pending migrations on startup
+//	6. pageSize - how many snippets are listed on a page
+//	7. pasteLimit - the largest paste, in bytes
+//	8. expiry - the default and maximum snippet expiry
+//	9. shutdownTimeout - how long to wait for requests
+//		 to finish when stopping
+//	10. tls - the TLS certificate and key files
+//	11. sessionLifetime - how long a login lasts
+//	12. timeouts - the HTTP server's timeouts
+//	13. csp - the Content-Security-Policy header
+//	14. printConfig - print the settings and exit, rather
+//			than starting the server
+//	15. plainHTTP - serve plain HTTP, for running behind
+//			a reverse proxy which handles TLS
+//	16. trustedProxies - the networks of the reverse
+//			proxies whose X-Forwarded-* headers are believed
+//	17. devTLS - serve HTTPS with a self-signed
+//			certificate generated in memory on startup
+//	18. certHosts - the host names and IP addresses
+//			generated certificates are for
+type config struct {
+	addr				string
+	dsn					string
+	autoMigrate	bool
+	pageSize		int
+	pasteLimit	int64
+	expiry			expiryConfig
+	shutdownTimeout	time.Duration
+	sweep				struct {
+		interval	time.Duration
+		batch			int
+	}
+	tls					struct {
+		certFile	string
+		keyFile		string
+	}
+	sessionLifetime	time.Duration
+	timeouts		struct {
+		idle	time.Duration
+		read	time.Duration
+		write	time.Duration
+	}
+	csp					string
+	printConfig	bool
+	plainHTTP		bool
+	trustedProxies	ipPrefixes
+	devTLS			bool
+	certHosts		commaList
+}
+
+// defaultCSP is the Content-Security-Policy sent with
+// every response unless the csp setting changes it. It
+// allows no inline scripts or styles.
+const defaultCSP = "default-src 'self'; style-src 'self' fonts.googleapis.com; font-src fonts-gstatic.com"
+
+// envPrefix starts the name of the environment variable
+// for each setting, like SNIPPETBOX_PAGE_SIZE for
+// -page-size.
+const envPrefix = "SNIPPETBOX_"
+
+// commandLineOnly lists the flags which aren't
+// settings, so can't be set in a config file and
+// aren't printed by -print-config. The config file
+// itself can still be chosen with SNIPPETBOX_CONFIG.
+var commandLineOnly = map[string]bool{
+	"config":       true,
+	"print-config": true,
+}
+
+/*
+	defineFlags function defines a flag for every
+	setting on fs, with its default value, storing the
+	values in cfg.
+*/
+func defineFlags(fs *flag.FlagSet, cfg *config) {
+	fs.StringVar(&cfg.addr, "addr", ":8000", "HTTP network address")
+	fs.StringVar(&cfg.dsn, "dsn", "./snippetbox.db", "SQLite data source file name")
+	fs.DurationVar(&cfg.sweep.interval, "sweep-interval", time.Hour, "Time between purges of expired snippets and sessions (0 disables purging)")
+	fs.IntVar(&cfg.sweep.batch, "sweep-batch", 500, "Maximum rows removed by each purge query")
+	fs.BoolVar(&cfg.autoMigrate, "auto-migrate", true, "Apply pending database migrations on startup")
+	fs.IntVar(&cfg.pageSize, "page-size", 10, "Number of snippets listed on each page")
+	fs.Int64Var(&cfg.pasteLimit, "paste-limit", 1<<20, "Largest body, in bytes, accepted by the raw paste endpoint")
+	fs.StringVar(&cfg.expiry.def, "default-expiry", "1y", "Expiry chosen for new snippets: "+strings.Join(expiryKeys(), ", "))
+	fs.DurationVar(&cfg.shutdownTimeout, "shutdown-timeout", 30*time.Second, "Time allowed for requests in progress to finish after SIGINT or SIGTERM")
+	fs.DurationVar(&cfg.expiry.max, "max-expiry", 0, "Longest a snippet can last, like 720h (0 for no maximum, which allows snippets that never expire)")
+	fs.StringVar(&cfg.tls.certFile, "tls-cert", "./tls/cert.pem", "TLS certificate file")
+	fs.StringVar(&cfg.tls.keyFile, "tls-key", "./tls/key.pem", "TLS private key file")
+	fs.DurationVar(&cfg.sessionLifetime, "session-lifetime", 12*time.Hour, "How long a login lasts")
+	fs.DurationVar(&cfg.timeouts.idle, "idle-timeout", time.Minute, "Longest a keep-alive connection waits for its next request")
+	fs.DurationVar(&cfg.timeouts.read, "read-timeout", 5*time.Second, "Longest time allowed for reading a request")
+	fs.DurationVar(&cfg.timeouts.write, "write-timeout", 10*time.Second, "Longest time allowed for writing a response")
+	fs.StringVar(&cfg.csp, "csp", defaultCSP, "Content-Security-Policy header sent with every response (empty to send none)")
+	fs.BoolVar(&cfg.plainHTTP, "plain-http", false, "Serve plain HTTP rather than HTTPS, for running behind a reverse proxy which handles TLS")
+	fs.Var(&cfg.trustedProxies, "trusted-proxies", "Comma separated CIDRs of reverse proxies whose X-Forwarded-For and X-Forwarded-Proto headers are trusted")
+	fs.BoolVar(&cfg.devTLS, "dev-tls", false, "Serve HTTPS with a self-signed certificate generated on startup, for development")
+	cfg.certHosts = commaList{"localhost", "127.0.0.1", "::1"}
+	fs.Var(&cfg.certHosts, "cert-hosts", "Comma separated host names and IP addresses for certificates made by -dev-tls and gencert")
+	fs.BoolVar(&cfg.printConfig, "print-config", false, "Print the settings, with secrets redacted, and exit")
+}
+
+/*
+	loadConfig function reads the settings from the
+	command line arguments in args, the environment
+	variables found by lookupEnv, and the JSON config
+	file named by the -config flag or the
+	SNIPPETBOX_CONFIG environment variable. Flags take
+	precedence over environment variables, which take
+	precedence over the config file, which takes
+	precedence over the defaults. The settings are
+	validated, and the flag set is returned too, for
+	the arguments after the flags and for -print-config.
+*/
+func loadConfig(args []string, lookupEnv func(string) (string, bool)) (*config, *flag.FlagSet, error) {
+	cfg := &config{}
+
+	fs := flag.NewFlagSet("snippetbox", flag.ExitOnError)
+	defineFlags(fs, cfg)
+	configFile := fs.String("config", "", "JSON file, ending in .json, to read settings from, keyed by flag name")
+
+	fs.Parse(args)
+
+	// Note which flags were given on the command line, so
+	// they aren't overridden
+	given := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) {
+		given[f.Name] = true
+	})
+
+	path := *configFile
+	if env, ok := lookupEnv(envPrefix + "CONFIG"); ok && !given["config"] {
+		path = env
+	}
+
+	if path != "" {
+		err := applyConfigFile(fs, path, given)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	err := applyEnv(fs, lookupEnv, given)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	err = cfg.validate()
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid configuration:\n%w", err)
+	}
+
+	return cfg, fs, nil
+}
+
+/*
+	applyConfigFile function sets the flags named in the
+	JSON object in the file at path, except for those
+	given on the command line. Values can be strings,
+	numbers or booleans, and are parsed in the same way
+	as the flags, so durations are strings like "90s".
+	Unknown settings are an error, as they are most
+	likely a typo. Only JSON is supported, so files
+	without a .json extension are refused rather than
+	misread.
+*/
+func applyConfigFile(fs *flag.FlagSet, path string, given map[string]bool) error {
+	if ext := filepath.Ext(path); !strings.EqualFold(ext, ".json") {
+		return fmt.Errorf("config file %s: only JSON config files are supported, and the file name must end in .json", path)
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("reading config file: %w", err)
+	}
+
+	// Keep numbers as they were written, so large
+	// integers aren't turned into floats
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+
+	var settings map[string]any
+	err = dec.Decode(&settings)
+	if err != nil {
+		return fmt.Errorf("config file %s: %w", path, err)
+	}
+
+	// Go through the settings in order, so the same file
+	// always gives the same first error
+	names := make([]string, 0, len(settings))
+	for name := range settings {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if fs.Lookup(name) == nil || commandLineOnly[name] {
+			return fmt.Errorf("config file %s: unknown setting %q", path, name)
+		}
+		if given[name] {
+			continue
+		}
+
+		var value string
+		switch v := settings[name].(type) {
+		case string:
+			value = v
+		case json.Number:
+			value = v.String()
+		case bool:
+			value = strconv.FormatBool(v)
+		default:
+			return fmt.Errorf("config file %s: %q must be a string, number or boolean", path, name)
+		}
+
+		err = fs.Set(name, value)
+		if err != nil {
+			return fmt.Errorf("config file %s: invalid value %q for %q: %w", path, value, name, err)
+		}
+	}
+
+	return nil
+}
+
+/*
+	applyEnv function sets each flag from its
+	environment variable, if there is one, unless the
+	flag was given on the command line.
+*/
+func applyEnv(fs *flag.FlagSet, lookupEnv func(string) (string, bool), given map[string]bool) error {
+	var err error
+
+	fs.VisitAll(func(f *flag.Flag) {
+		if err != nil || given[f.Name] || commandLineOnly[f.Name] {
+			return
+		}
+
+		name := envName(f.Name)
+		value, ok := lookupEnv(name)
+		if !ok {
+			return
+		}
+
+		if setErr := fs.Set(f.Name, value); setErr != nil {
+			err = fmt.Errorf("invalid value %q for %s: %w", value, name, setErr)
+		}
+	})
+
+	return err
+}
+
+/*
+	envName function returns the name of the environment
+	variable for the flag with the given name.
+*/
+func envName(flagName string) string {
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(flagName, "-", "_"))
+}
+
+/*
+	validate function checks the settings make sense,
+	returning every problem found rather than just the
+	first, so they can all be fixed at once.
+*/
+func (cfg *config) validate() error {
+	var errs []error
+
+	check := func(ok bool, message string) {
+		if !ok {
+			errs = append(errs, errors.New(message))
+		}
+	}
+
+	// A purge has to remove at least one row at a time,
+	// otherwise it would never finish
+	check(cfg.sweep.batch >= 1, "-sweep-batch must be at least 1")
+	check(cfg.sweep.interval >= 0, "-sweep-interval must not be negative")
+
+	// Keep pages of snippets to a sensible size
+	check(cfg.pageSize >= 1 && cfg.pageSize <= 100, "-page-size must be between 1 and 100")
+
+	// A paste has to be allowed some content
+	check(cfg.pasteLimit >= 1, "-paste-limit must be at least 1")
+
+	// The default expiry has to be one the maximum
+	// allows
+	if err := cfg.expiry.validate(); err != nil {
+		errs = append(errs, err)
+	}
+
+	// Stopping has to allow some time for requests to
+	// finish
+	check(cfg.shutdownTimeout > 0, "-shutdown-timeout must be more than 0")
+
+	// The certificate files are only needed when serving
+	// HTTPS with a certificate which isn't generated
+	if !cfg.plainHTTP && !cfg.devTLS {
+		check(cfg.tls.certFile != "", "-tls-cert must not be empty")
+		check(cfg.tls.keyFile != "", "-tls-key must not be empty")
+	}
+	check(!cfg.plainHTTP || !cfg.devTLS, "-dev-tls and -plain-http can't be used together")
+	check(len(cfg.certHosts) > 0, "-cert-hosts must not be empty")
+	check(cfg.sessionLifetime > 0, "-session-lifetime must be more than 0")
+
+	// A timeout of 0 means no timeout, which is allowed
+	check(cfg.timeouts.idle >= 0, "-idle-timeout must not be negative")
+	check(cfg.timeouts.read >= 0, "-read-timeout must not be negative")
+	check(cfg.timeouts.write >= 0, "-write-timeout must not be negative")
+
+	return errors.Join(errs...)
+}
+
+/*
+	writeConfig function writes the settings in fs to w
+	as a JSON object keyed by flag name, which can be
+	used as a config file. Secrets in the DSN, like
+	passwords and encryption keys, are redacted.
+*/
+func writeConfig(w io.Writer, fs *flag.FlagSet) error {
+	settings := map[string]any{}
+
+	fs.VisitAll(func(f *flag.Flag) {
+		if commandLineOnly[f.Name] {
+			return
+		}
+
+		// Keep numbers and booleans as JSON numbers and
+		// booleans. Everything else, like durations, is
+		// written the way the flag parses it.
+		var value any = f.Value.String()
+		if getter, ok := f.Value.(flag.Getter); ok {
+			switch v := getter.Get().(type) {
+			case bool, int, int64, uint, uint64:
+				value = v
+			}
+		}
+
+		if f.Name == "dsn" {
+			value = redactDSN(f.Value.String())
+		}
+
+		settings[f.Name] = value
+	})
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "\t")
+	return enc.Encode(settings)
+}
+
+// redacted replaces secret values printed by
+// -print-config.
+const redacted = "REDACTED"
+
+/*
+	redactDSN function returns dsn with the values of
+	any query parameters that hold secrets replaced,
+	such as go-sqlite3's _auth_pass and the _key of
+	encrypted databases.
+*/
+func redactDSN(dsn string) string {
+	base, query, ok := strings.Cut(dsn, "?")
+	if !ok {
+		return dsn
+	}
+
+	params := strings.Split(query, "&")
+	for i, param := range params {
+		name, _, _ := strings.Cut(param, "=")
+		lower := strings.ToLower(name)
+
+		for _, secret := range []string{"pass", "key", "secret", "token"} {
+			if strings.Contains(lower, secret) {
+				params[i] = name + "=" + redacted
+				break
+			}
+		}
+	}
+
+	return base + "?" + strings.Join(params, "&")
+}
+
+// commaList defines a type to hold a setting which is
+// a list of strings. It is a flag.Value, set from a
+// comma separated list.
+type commaList []string
+
+/*
+	String function returns the list as the comma
+	separated list it is set from.
+*/
+func (l *commaList) String() string {
+	if l == nil {
+		return ""
+	}
+	return strings.Join(*l, ",")
+}
+
+/*
+	Set function replaces the list with the non-empty
+	items in the comma separated list in value.
+*/
+func (l *commaList) Set(value string) error {
+	var items commaList
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+
+	*l = items
+	return nil
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	err := os.WriteFile(path, []byte(`{"addr": ":9000", "page-size": 20, "auto-migrate": false, "idle-timeout": "2m"}`), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	env := map[string]string{
+		"SNIPPETBOX_CONFIG":    path,
+		"SNIPPETBOX_PAGE_SIZE": "30",
+		"SNIPPETBOX_ADDR":      ":9001",
+	}
+	lookupEnv := func(name string) (string, bool) {
+		value, ok := env[name]
+		return value, ok
+	}
+
+	cfg, flags, err := loadConfig([]string{"-addr", ":9002", "migrate", "status"}, lookupEnv)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Flags beat environment variables, which beat the
+	// config file, which beats the defaults
+	if cfg.addr != ":9002" {
+		t.Errorf("got addr %q, want the flag", cfg.addr)
+	}
+	if cfg.pageSize != 30 {
+		t.Errorf("got page-size %d, want the environment variable", cfg.pageSize)
+	}
+	if cfg.autoMigrate || cfg.timeouts.idle != 2*time.Minute {
+		t.Errorf("got auto-migrate %t and idle-timeout %s, want the config file", cfg.autoMigrate, cfg.timeouts.idle)
+	}
+	if cfg.sessionLifetime != 12*time.Hour {
+		t.Errorf("got session-lifetime %s, want the default", cfg.sessionLifetime)
+	}
+
+	if got := strings.Join(flags.Args(), " "); got != "migrate status" {
+		t.Errorf("got arguments %q, want %q", got, "migrate status")
+	}
+}
+
+func TestLoadConfigErrors(t *testing.T) {
+	noEnv := func(string) (string, bool) { return "", false }
+
+	tests := []struct {
+		name	string
+		file	string
+		args	[]string
+		want	string
+	}{
+		{"Unknown setting", `{"page-sise": 20}`, nil, `unknown setting "page-sise"`},
+		{"Bad value", `{"read-timeout": "soon"}`, nil, `invalid value "soon" for "read-timeout"`},
+		{"Wrong type", `{"addr": [":9000"]}`, nil, `"addr" must be a string, number or boolean`},
+		{"Invalid settings", `{}`, []string{"-page-size", "0", "-tls-cert", ""}, "-page-size must be between 1 and 100\n-tls-cert must not be empty"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.json")
+			err := os.WriteFile(path, []byte(tt.file), 0600)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, _, err = loadConfig(append([]string{"-config", path}, tt.args...), noEnv)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("got error %v, want one containing %q", err, tt.want)
+			}
+		})
+	}
+
+	// Other formats, like TOML and YAML, aren't supported
+	for _, name := range []string{"config.toml", "config.yaml", "config"} {
+		t.Run("File named "+name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), name)
+			err := os.WriteFile(path, []byte(`addr = ":9000"`), 0600)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, _, err = loadConfig([]string{"-config", path}, noEnv)
+			want := "only JSON config files are supported"
+			if err == nil || !strings.Contains(err.Error(), want) {
+				t.Errorf("got error %v, want one containing %q", err, want)
+			}
+		})
+	}
+}
+
+func TestWriteConfig(t *testing.T) {
+	noEnv := func(string) (string, bool) { return "", false }
+
+	_, flags, err := loadConfig([]string{"-dsn", "file:sb.db?_auth_user=admin&_auth_pass=hunter2", "-print-config"}, noEnv)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	err = writeConfig(&buf, flags)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var settings map[string]any
+	err = json.Unmarshal(buf.Bytes(), &settings)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := settings["dsn"], "file:sb.db?_auth_user=admin&_auth_pass=REDACTED"; got != want {
+		t.Errorf("got dsn %q, want %q", got, want)
+	}
+	if got := settings["page-size"]; got != float64(10) {
+		t.Errorf("got page-size %v, want the number 10", got)
+	}
+	if _, ok := settings["print-config"]; ok {
+		t.Error("got print-config, want it left out")
+	}
+}
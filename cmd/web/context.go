@@ -5,4 +5,19 @@ type contextKey string
 
 // Set the isAuthenticatedContextKey constant key
 // to "isAuthenticated"
-const isAuthenticatedContextKey = contextKey("isAuthenticated")
\ No newline at end of file
+const isAuthenticatedContextKey = contextKey("isAuthenticated")
+// Set the authenticatedUserIDContextKey constant key
+// to "authenticatedUserID". It holds the ID of the
+// user making the request, whether they logged in to a
+// session or authenticated to the API.
+const authenticatedUserIDContextKey = contextKey("authenticatedUserID")
+
+// Set the apiTokenContextKey constant key to
+// "apiToken". It holds the *models.Token an API request
+// was authenticated with.
+const apiTokenContextKey = contextKey("apiToken")
+
+// Set the isSecureContextKey constant key to
+// "isSecure". It holds whether the client made the
+// request using HTTPS, which realClient works out.
+const isSecureContextKey = contextKey("isSecure")
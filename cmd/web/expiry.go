@@ -0,0 +1,270 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/robwestbrook/snippetbox/internal/models"
+)
+
+// expiryOption defines a type to hold one of the
+// choices offered for how long a snippet lasts.
+// Contains:
+//	1. Key - sent by the snippet forms and the API
+//	2. Name - shown to users
+//	3. Duration - how long the snippet lasts, or 0 if
+//								it never expires
+type expiryOption struct {
+	Key				string
+	Name			string
+	Duration	time.Duration
+}
+
+// expiryNever is the key of the option for snippets
+// which never expire.
+const expiryNever = "never"
+
+// expiryOptions lists the choices for how long a
+// snippet lasts, shortest first. The server's maximum
+// expiry can rule some of them out.
+var expiryOptions = []expiryOption{
+	{Key: "10m", Name: "Ten minutes", Duration: 10 * time.Minute},
+	{Key: "1h", Name: "One hour", Duration: time.Hour},
+	{Key: "1d", Name: "One day", Duration: 24 * time.Hour},
+	{Key: "1w", Name: "One week", Duration: 7 * 24 * time.Hour},
+	{Key: "1mo", Name: "One month", Duration: 30 * 24 * time.Hour},
+	{Key: "1y", Name: "One year", Duration: 365 * 24 * time.Hour},
+	{Key: expiryNever, Name: "Never"},
+}
+
+// maxExpiryDays is the largest number of days a
+// snippet can be asked to last for. Snippets which
+// should last longer can be made to never expire.
+const maxExpiryDays = 100 * 365
+
+// expiryInputLayout is the format of the date and time
+// sent by the datetime-local inputs on the snippet
+// forms. The time is taken to be UTC, which is how
+// dates are shown everywhere else.
+const expiryInputLayout = "2006-01-02T15:04"
+
+/*
+	lookupExpiry function returns the expiry option with
+	the given key, and whether there is one.
+*/
+func lookupExpiry(key string) (expiryOption, bool) {
+	for _, o := range expiryOptions {
+		if o.Key == key {
+			return o, true
+		}
+	}
+	return expiryOption{}, false
+}
+
+// expiryConfig defines a type to hold the server wide
+// expiry settings.
+// Contains:
+//	1. def - the key of the option chosen by default
+//	2. max - the longest a snippet can last, or 0 for
+//					 no maximum, which allows snippets that never
+//					 expire
+type expiryConfig struct {
+	def	string
+	max	time.Duration
+}
+
+/*
+	allows function returns true if a snippet may last
+	for d. A d of 0 means the snippet never expires.
+*/
+func (c expiryConfig) allows(d time.Duration) bool {
+	if c.max == 0 {
+		return true
+	}
+	return d > 0 && d <= c.max
+}
+
+/*
+	choices function returns the expiry options allowed
+	by the maximum expiry, for the snippet forms.
+*/
+func (c expiryConfig) choices() []expiryOption {
+	var choices []expiryOption
+	for _, o := range expiryOptions {
+		if c.allows(o.Duration) {
+			choices = append(choices, o)
+		}
+	}
+	return choices
+}
+
+/*
+	validate function checks the settings make sense
+	together, so mistakes are caught at startup.
+*/
+func (c expiryConfig) validate() error {
+	if c.max < 0 {
+		return fmt.Errorf("-max-expiry must not be negative")
+	}
+
+	def, ok := lookupExpiry(c.def)
+	if !ok {
+		return fmt.Errorf("-default-expiry must be one of %s", strings.Join(expiryKeys(), ", "))
+	}
+	if !c.allows(def.Duration) {
+		return fmt.Errorf("-default-expiry %s is longer than -max-expiry %s", c.def, c.max)
+	}
+
+	if len(c.choices()) == 0 {
+		return fmt.Errorf("-max-expiry %s is shorter than every expiry option", c.max)
+	}
+
+	return nil
+}
+
+/*
+	expires function works out when a snippet expires
+	from the expires and expires_at fields of a form or
+	API request, counting from now. A date in at, if
+	there is one, is used instead of the option in key.
+	As well as the option keys, key can be a whole
+	number of days, which is what the forms and the API
+	used to send. The time returned is always in UTC,
+	like every time stored. If the fields aren't valid,
+	or ask for longer than the maximum expiry, a message
+	saying why is returned instead.
+*/
+func (c expiryConfig) expires(key string, at string, now time.Time) (time.Time, string) {
+	now = now.UTC()
+
+	var d time.Duration
+	var until time.Time
+
+	switch days, err := strconv.Atoi(key); {
+	case at != "":
+		t, err := parseExpiresAt(at)
+		if err != nil {
+			return time.Time{}, "This field must be a date and time, like 2030-01-31T12:00"
+		}
+		if !t.After(now) {
+			return time.Time{}, "This date must be in the future"
+		}
+		if t.After(models.Never) {
+			return time.Time{}, "This date must be before the year 10000"
+		}
+		// Sub() stops at the longest duration, about 292
+		// years, which is still longer than any maximum
+		d = t.Sub(now)
+		until = t
+	case err == nil:
+		if days < 1 {
+			return time.Time{}, "This field must be at least 1 day"
+		}
+		// Check the days before turning them into a
+		// duration, which would overflow for huge numbers
+		if days > maxExpiryDays {
+			return time.Time{}, fmt.Sprintf("This field must be at most %d days", maxExpiryDays)
+		}
+		d = time.Duration(days) * 24 * time.Hour
+	default:
+		o, ok := lookupExpiry(key)
+		if !ok {
+			return time.Time{}, "This field must be one of the options listed"
+		}
+		d = o.Duration
+	}
+
+	if !c.allows(d) {
+		return time.Time{}, fmt.Sprintf("Snippets can't last longer than %s", formatDuration(c.max))
+	}
+	if d == 0 {
+		return models.Never, ""
+	}
+	if !until.IsZero() {
+		return until, ""
+	}
+
+	return now.Add(d), ""
+}
+
+/*
+	formatDuration function formats d for people,
+	counting in days if it is a whole number of them,
+	and otherwise leaving off zero minutes and seconds,
+	so 168h is "7 days" and 1h30m is "1h30m".
+*/
+func formatDuration(d time.Duration) string {
+	day := 24 * time.Hour
+	if d >= day && d%day == 0 {
+		if d == day {
+			return "1 day"
+		}
+		return fmt.Sprintf("%d days", d/day)
+	}
+
+	s := d.String()
+	if strings.HasSuffix(s, "m0s") {
+		s = s[:len(s)-2]
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = s[:len(s)-2]
+	}
+	return s
+}
+
+/*
+	parseExpiresAt function parses the date and time a
+	snippet should expire, in UTC. The API sends RFC 3339
+	times, which can be in any time zone, and the forms
+	send datetime-local values, which are taken to be UTC.
+*/
+func parseExpiresAt(at string) (time.Time, error) {
+	t, err := time.Parse(time.RFC3339, at)
+	if err == nil {
+		return t.UTC(), nil
+	}
+	return time.ParseInLocation(expiryInputLayout, at, time.UTC)
+}
+
+/*
+	expiryKeys function returns the key of every expiry
+	option.
+*/
+func expiryKeys() []string {
+	keys := make([]string, len(expiryOptions))
+	for i, o := range expiryOptions {
+		keys[i] = o.Key
+	}
+	return keys
+}
+
+// expiresInput defines a type to hold the expires field
+// of a JSON request: one of the expiry option keys, or a
+// number of days, as earlier versions of the API took.
+type expiresInput string
+
+/*
+	UnmarshalJSON function accepts either a string or a
+	number for the expires field. The decoder doesn't
+	say which field a custom type failed on, so the
+	error names it.
+*/
+func (e *expiresInput) UnmarshalJSON(b []byte) error {
+	var days int
+	if err := json.Unmarshal(b, &days); err == nil {
+		*e = expiresInput(strconv.Itoa(days))
+		return nil
+	}
+
+	var key string
+	if err := json.Unmarshal(b, &key); err != nil {
+		return &json.UnmarshalTypeError{Value: string(b), Type: reflect.TypeOf(key), Field: "expires"}
+	}
+
+	*e = expiresInput(key)
+	return nil
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/robwestbrook/snippetbox/internal/models"
+)
+
+func TestExpires(t *testing.T) {
+	now := time.Date(2024, 1, 25, 17, 30, 0, 0, time.UTC)
+	unlimited := expiryConfig{def: "1y"}
+	week := expiryConfig{def: "1d", max: 7 * 24 * time.Hour}
+
+	// An empty want means the fields should be refused
+	tests := []struct {
+		name		string
+		limits	expiryConfig
+		key			string
+		at			string
+		want		time.Time
+	}{
+		{"Option", unlimited, "10m", "", now.Add(10 * time.Minute)},
+		{"Never", unlimited, "never", "", models.Never},
+		{"Days", unlimited, "7", "", now.AddDate(0, 0, 7)},
+		{"Zero days", unlimited, "0", "", time.Time{}},
+		{"Most days", unlimited, "36500", "", now.AddDate(0, 0, 36500)},
+		{"Too many days", unlimited, "106752", "", time.Time{}},
+		{"Far future date", unlimited, "", "2500-06-01T00:00:00Z", time.Date(2500, 6, 1, 0, 0, 0, 0, time.UTC)},
+		{"Unknown option", unlimited, "fortnight", "", time.Time{}},
+		{"Date", unlimited, "1y", "2024-02-01T09:00", time.Date(2024, 2, 1, 9, 0, 0, 0, time.UTC)},
+		{"RFC 3339 date", unlimited, "", "2024-02-01T10:00:00+01:00", time.Date(2024, 2, 1, 9, 0, 0, 0, time.UTC)},
+		{"Past date", unlimited, "1y", "2024-01-01T09:00", time.Time{}},
+		{"Bad date", unlimited, "1y", "tomorrow", time.Time{}},
+		{"Within maximum", week, "1w", "", now.AddDate(0, 0, 7)},
+		{"Over maximum", week, "1mo", "", time.Time{}},
+		{"Never over maximum", week, "never", "", time.Time{}},
+		{"Date over maximum", week, "", "2024-03-01T09:00", time.Time{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, message := tt.limits.expires(tt.key, tt.at, now)
+
+			if tt.want.IsZero() {
+				if message == "" {
+					t.Errorf("got %v, want an error", got)
+				}
+				return
+			}
+			if message != "" {
+				t.Fatalf("got error %q", message)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExpiresInUTC(t *testing.T) {
+	// Now is given in a time zone ahead of UTC, but the
+	// expiry times all have to come back in UTC, as the
+	// same instant
+	now := time.Date(2024, 1, 25, 23, 30, 0, 0, time.FixedZone("UTC+5", 5*60*60))
+	limits := expiryConfig{def: "1y"}
+
+	tests := []struct {
+		name	string
+		key		string
+		at		string
+		want	time.Time
+	}{
+		{"Option", "1h", "", time.Date(2024, 1, 25, 19, 30, 0, 0, time.UTC)},
+		{"Days", "1", "", time.Date(2024, 1, 26, 18, 30, 0, 0, time.UTC)},
+		{"Date", "", "2024-02-01T09:00", time.Date(2024, 2, 1, 9, 0, 0, 0, time.UTC)},
+		{"RFC 3339 date", "", "2024-02-01T09:00:00-08:00", time.Date(2024, 2, 1, 17, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, message := limits.expires(tt.key, tt.at, now)
+			if message != "" {
+				t.Fatalf("got error %q", message)
+			}
+			if got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExpiryConfigValidate(t *testing.T) {
+	tests := []struct {
+		name		string
+		limits	expiryConfig
+		valid		bool
+	}{
+		{"Defaults", expiryConfig{def: "1y"}, true},
+		{"Never without maximum", expiryConfig{def: "never"}, true},
+		{"Never with maximum", expiryConfig{def: "never", max: time.Hour}, false},
+		{"Default over maximum", expiryConfig{def: "1w", max: 24 * time.Hour}, false},
+		{"Unknown default", expiryConfig{def: "2y"}, false},
+		{"Maximum below every option", expiryConfig{def: "10m", max: time.Minute}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.limits.validate()
+
+			if valid := err == nil; valid != tt.valid {
+				t.Errorf("got error %v, want valid %t", err, tt.valid)
+			}
+		})
+	}
+}
+
+func TestFormatDuration(t *testing.T) {
+	tests := []struct {
+		d			time.Duration
+		want	string
+	}{
+		{24 * time.Hour, "1 day"},
+		{7 * 24 * time.Hour, "7 days"},
+		{time.Hour, "1h"},
+		{90 * time.Minute, "1h30m"},
+		{30 * time.Second, "30s"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			if got := formatDuration(tt.d); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,22 +1,675 @@
 package main
 
 import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"mime"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/julienschmidt/httprouter"
+	"github.com/robwestbrook/snippetbox/internal/diff"
 	"github.com/robwestbrook/snippetbox/internal/models"
 	"github.com/robwestbrook/snippetbox/internal/validator"
 )
 
 /*
-	Home function is the handler for the home page
+	Home function is the handler for the home page,
+	which lists the latest snippets a page at a time.
 */
 func (app *application) home(w http.ResponseWriter, r *http.Request) {
-	// Get the latest snippets
-	snippets, err := app.snippets.Latest()
+	// Get the requested page of the latest snippets
+	page, ok := app.listSnippets(w, r, models.ListOptions{Sort: models.DefaultSort})
+	if !ok {
+		return
+	}
+
+	// Call the newTemplateData()helper to get a
+	// templateData struct containing the default
+	// data and add the page of snippets to it.
+	data := app.newTemplateData(r)
+	data.Snippets = page.Snippets
+	data.Page = page
+
+	// Render the page
+	app.render(w, http.StatusOK, "home.tmpl", data)
+}
+
+/*
+	snippetArchive function lists every unexpired
+	snippet a page at a time, in the order chosen with
+	the "sort" query string parameter.
+*/
+func (app *application) snippetArchive(w http.ResponseWriter, r *http.Request) {
+	// Get the sort order, defaulting to newest first
+	sort := r.URL.Query().Get("sort")
+	if sort == "" {
+		sort = models.DefaultSort
+	}
+
+	// Get the requested page of snippets
+	page, ok := app.listSnippets(w, r, models.ListOptions{Sort: sort})
+	if !ok {
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data.Snippets = page.Snippets
+	data.Page = page
+	data.Sort = sort
+
+	// Render the page
+	app.render(w, http.StatusOK, "archive.tmpl", data)
+}
+
+/*
+	snippetTag function lists the unexpired snippets
+	with the tag in the URL a page at a time, newest
+	first.
+*/
+func (app *application) snippetTag(w http.ResponseWriter, r *http.Request) {
+	// Get the tag from the URL. No snippet can have a
+	// tag that isn't valid, so send a 404 for those.
+	params := httprouter.ParamsFromContext(r.Context())
+
+	tag := params.ByName("name")
+	if !validator.Matches(tag, validator.TagRX) {
+		app.notFound(w)
+		return
+	}
+
+	// Get the requested page of snippets with the tag
+	page, ok := app.listSnippets(w, r, models.ListOptions{Sort: models.DefaultSort, Tag: tag})
+	if !ok {
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data.Snippets = page.Snippets
+	data.Page = page
+	data.Tag = tag
+
+	// Render the page
+	app.render(w, http.StatusOK, "tag.tmpl", data)
+}
+
+/*
+	tagCloud function displays every tag in use, with
+	the number of snippets it is on.
+*/
+func (app *application) tagCloud(w http.ResponseWriter, r *http.Request) {
+	tags, err := app.snippets.Tags()
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data.Tags = tags
+
+	// Render the page
+	app.render(w, http.StatusOK, "tags.tmpl", data)
+}
+
+/*
+	listSnippets function gets the page of snippets
+	chosen by the "after" or "before" cursors in the
+	query string, using the sort order and tag in opts.
+	A bad cursor or sort order sends a 400 Bad Request
+	response, false is returned and the caller should
+	stop handling the request.
+*/
+func (app *application) listSnippets(w http.ResponseWriter, r *http.Request, opts models.ListOptions) (*models.Page, bool) {
+	query := r.URL.Query()
+
+	opts.After = query.Get("after")
+	opts.Before = query.Get("before")
+	opts.Limit = app.config.pageSize
+
+	page, err := app.snippets.List(opts)
+	if err != nil {
+		if errors.Is(err, models.ErrInvalidCursor) || errors.Is(err, models.ErrInvalidSort) {
+			app.clientError(w, http.StatusBadRequest)
+		} else {
+			app.serverError(w, err)
+		}
+		return nil, false
+	}
+
+	return page, true
+}
+
+// searchPageSize is the number of results shown on
+// each page of search results.
+const searchPageSize = 10
+
+/*
+	search function handles the search results page. The
+	words to search for are in the "q" query string
+	parameter, and the page of results in "page".
+*/
+func (app *application) search(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	// Get the page number, defaulting to the first page
+	page := 1
+	if v := query.Get("page"); v != "" {
+		var err error
+		page, err = strconv.Atoi(v)
+		if err != nil || page < 1 {
+			app.clientError(w, http.StatusBadRequest)
+			return
+		}
+	}
+
+	data := app.newTemplateData(r)
+	data.Query = query.Get("q")
+
+	// Check the search isn't too long
+	var v validator.Validator
+	v.CheckField(
+		validator.MaxChars(data.Query, 200),
+		"q",
+		"This field cannot be more than 200 characters long")
+
+	data.Form = v
+
+	if !v.Valid() {
+		app.render(w, http.StatusUnprocessableEntity, "search.tmpl", data)
+		return
+	}
+
+	// Ask for one more result than fits on the page, to
+	// find out if there is a next page
+	results, err := app.snippets.Search(data.Query, searchPageSize+1, (page-1)*searchPageSize)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	if len(results) > searchPageSize {
+		results = results[:searchPageSize]
+		data.NextPage = page + 1
+	}
+	data.PrevPage = page - 1
+	data.Results = results
+
+	// Render the page
+	app.render(w, http.StatusOK, "search.tmpl", data)
+}
+
+/*
+	lookupSnippet function gets the snippet named by the
+	"slug" parameter in the URL, as the logged in user may
+	view it. The parameter is stored in the request
+	context. Retrieve it using the ParamsFromContext(),
+	which returns a slice of parameter names and values.
+	Slugs always start with a letter, so a number is the
+	old ID of a legacy snippet instead.
+	models.ErrNoRecord is returned if there is no such
+	snippet, or the user can't view it that way.
+*/
+func (app *application) lookupSnippet(r *http.Request) (*models.Snippet, error) {
+	// Get request paramters
+	params := httprouter.ParamsFromContext(r.Context())
+	slug := params.ByName("slug")
+
+	// Look up numbers as the ID of a legacy snippet
+	id, err := strconv.Atoi(slug)
+	if err != nil {
+		return app.snippets.Get(slug, app.authenticatedUserID(r))
+	}
+	if id < 1 {
+		return nil, models.ErrNoRecord
+	}
+
+	return app.snippets.GetLegacy(id, app.authenticatedUserID(r))
+}
+
+/*
+	snippetFromURL function gets the snippet determined
+	by the snippet slug in the URL. If there is no such
+	snippet, or the logged in user can't view it, a 404
+	Not Found response is sent, false is returned and the
+	caller should stop handling the request. Private
+	snippets are hidden from other users with a 404 too,
+	so nobody can tell they exist.
+
+	Old links to legacy snippets, which used the ID, are
+	permanently redirected to the same page using the
+	slug. false is returned for those too.
+*/
+func (app *application) snippetFromURL(w http.ResponseWriter, r *http.Request) (*models.Snippet, bool) {
+	// Use the lookupSnippet() helper to retrieve data
+	// for the record. Return 404 if not found.
+	snippet, err := app.lookupSnippet(r)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, err)
+		}
+		return nil, false
+	}
+
+	// Redirect pages asked for by ID. The ID is the
+	// only number in the path before it, so swap the
+	// first one for the slug.
+	params := httprouter.ParamsFromContext(r.Context())
+	if id := params.ByName("slug"); id != snippet.Slug && r.Method == http.MethodGet {
+		u := *r.URL
+		u.Path = strings.Replace(u.Path, "/"+id, "/"+snippet.Slug, 1)
+		http.Redirect(w, r, u.String(), http.StatusMovedPermanently)
+		return nil, false
+	}
+
+	return snippet, true
+}
+
+/*
+	readableSnippet function gets the snippet in the URL
+	like snippetFromURL, for the pages other than the
+	snippet page which show its content. Burn after
+	reading snippets can only be read once, through
+	snippetRevealPost, so they are reported as not found
+	to everyone but their owner. Snippets with a password
+	have to be unlocked first.
+*/
+func (app *application) readableSnippet(w http.ResponseWriter, r *http.Request) (*models.Snippet, bool) {
+	snippet, ok := app.snippetFromURL(w, r)
+	if !ok {
+		return nil, false
+	}
+
+	if snippet.Burn && !snippet.OwnedBy(app.authenticatedUserID(r)) {
+		app.notFound(w)
+		return nil, false
+	}
+
+	// Send people to the snippet page to enter the
+	// password of a locked snippet
+	if !app.isUnlocked(r, snippet) {
+		http.Redirect(w, r, "/snippet/view/"+snippet.Slug, http.StatusSeeOther)
+		return nil, false
+	}
+
+	return snippet, true
+}
+
+/*
+	snippetView function handles a single snippet page
+	determined by snippet slug. Snippets with a password
+	show a form to unlock them instead, until the
+	password has been entered. Burn after reading
+	snippets aren't shown to anyone but their owner
+	straight away. Instead a page asks to confirm they
+	should be shown, which link previews and crawlers
+	won't do, as showing them deletes them.
+*/
+func (app *application) snippetView(w http.ResponseWriter, r *http.Request) {
+	// Get the snippet from the slug in the URL
+	snippet, ok := app.snippetFromURL(w, r)
+	if !ok {
+		return
+	}
+
+	// Call the newTemplateData()helper to get a
+	// templateData struct containing the default
+	// data and add the snippets slice to it.
+	data := app.newTemplateData(r)
+	data.Snippet = snippet
+	data.IsOwner = snippet.OwnedBy(app.authenticatedUserID(r))
+
+	if !app.isUnlocked(r, snippet) {
+		data.Form = snippetUnlockForm{}
+		app.render(w, http.StatusOK, "unlock.tmpl", data)
+		return
+	}
+
+	if snippet.Burn && !data.IsOwner {
+		app.render(w, http.StatusOK, "burn.tmpl", data)
+		return
+	}
+
+	// Render the page
+	app.render(w, http.StatusOK, "view.tmpl", data)
+}
+
+/*
+	snippetRevealPost function shows a burn after
+	reading snippet, deleting it at the same time, once
+	the confirmation form on the snippet page has been
+	sent. If someone else got there first, the snippet
+	is gone and a 404 Not Found is sent.
+*/
+func (app *application) snippetRevealPost(w http.ResponseWriter, r *http.Request) {
+	// Get the snippet from the slug in the URL
+	snippet, ok := app.snippetFromURL(w, r)
+	if !ok {
+		return
+	}
+
+	// A snippet with a password must be unlocked first
+	if !app.isUnlocked(r, snippet) {
+		http.Redirect(w, r, "/snippet/view/"+snippet.Slug, http.StatusSeeOther)
+		return
+	}
+
+	// Read and delete the snippet in one go
+	snippet, err := app.snippets.Burn(snippet.Slug, app.authenticatedUserID(r))
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, err)
+		}
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data.Snippet = snippet
+	data.Burned = true
+
+	// The snippet no longer exists, so make sure the
+	// page isn't kept anywhere either
+	w.Header().Set("Cache-Control", "no-store")
+
+	app.render(w, http.StatusOK, "view.tmpl", data)
+}
+
+// snippetUnlockForm struct takes in the password
+// entered to unlock a snippet.
+type snippetUnlockForm struct {
+	Password						string	`form:"password"`
+	validator.Validator					`form:"-"`
+}
+
+/*
+	snippetUnlockPost function checks the password
+	entered for a snippet. If it is right, the unlock is
+	remembered in the session for this snippet only, and
+	the user is sent back to the snippet page. Clients
+	which enter too many wrong passwords are made to
+	wait before they can try again.
+*/
+func (app *application) snippetUnlockPost(w http.ResponseWriter, r *http.Request) {
+	// Get the snippet from the slug in the URL
+	snippet, ok := app.snippetFromURL(w, r)
+	if !ok {
+		return
+	}
+
+	var form snippetUnlockForm
+
+	err := app.decodePostForm(r, &form)
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	// Turn away clients which have entered too many
+	// wrong passwords recently
+	ok, wait := app.unlocks.allow(clientIP(r))
+	if !ok {
+		seconds := int(wait.Seconds()) + 1
+		w.Header().Set("Retry-After", strconv.Itoa(seconds))
+		form.AddNonFieldError(fmt.Sprintf("Too many incorrect passwords, try again in %d seconds", seconds))
+
+		data := app.newTemplateData(r)
+		data.Snippet = snippet
+		data.Form = form
+		app.render(w, http.StatusTooManyRequests, "unlock.tmpl", data)
+		return
+	}
+
+	// Check the password. A snippet without a password
+	// doesn't need unlocking, so just show it.
+	err = app.snippets.Unlock(snippet.ID, form.Password)
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrInvalidCredentials):
+			app.unlocks.fail(clientIP(r))
+			form.AddNonFieldError("The password is incorrect")
+
+			data := app.newTemplateData(r)
+			data.Snippet = snippet
+			data.Form = form
+			app.render(w, http.StatusUnprocessableEntity, "unlock.tmpl", data)
+		case errors.Is(err, models.ErrNoRecord):
+			http.Redirect(w, r, "/snippet/view/"+snippet.Slug, http.StatusSeeOther)
+		default:
+			app.serverError(w, err)
+		}
+		return
+	}
+
+	// Remember the unlock and show the snippet
+	app.sessionManager.Put(r.Context(), unlockKey(snippet), true)
+
+	http.Redirect(w, r, "/snippet/view/"+snippet.Slug, http.StatusSeeOther)
+}
+
+/*
+	snippetRaw function serves the content of a snippet
+	as plain text, so it can be copied or fetched by
+	scripts without scraping the page.
+*/
+func (app *application) snippetRaw(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.readableSnippet(w, r)
+	if !ok {
+		return
+	}
+
+	app.serveSnippetContent(w, r, snippet)
+}
+
+/*
+	snippetDownload function serves the content of a
+	snippet as a file to save, named after its title
+	with the file extension of its language.
+*/
+func (app *application) snippetDownload(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.readableSnippet(w, r)
+	if !ok {
+		return
+	}
+
+	disposition := mime.FormatMediaType("attachment", map[string]string{
+		"filename": downloadName(snippet),
+	})
+	w.Header().Set("Content-Disposition", disposition)
+
+	app.serveSnippetContent(w, r, snippet)
+}
+
+/*
+	serveSnippetContent function sends the content of a
+	snippet as plain text. The ETag is a hash of the
+	content, so clients can cache it and check back with
+	If-None-Match to find out if it has been edited or
+	deleted. Only public snippets may be kept by shared
+	caches. http.ServeContent() handles the conditional
+	and range requests.
+*/
+func (app *application) serveSnippetContent(w http.ResponseWriter, r *http.Request, snippet *models.Snippet) {
+	hash := sha256.Sum256([]byte(snippet.Content))
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("ETag", `"`+hex.EncodeToString(hash[:16])+`"`)
+	if snippet.IsPublic() {
+		w.Header().Set("Cache-Control", "no-cache")
+	} else {
+		w.Header().Set("Cache-Control", "private, no-cache")
+	}
+
+	http.ServeContent(w, r, "", time.Time{}, strings.NewReader(snippet.Content))
+}
+
+/*
+	snippetHistory function displays every revision of
+	a snippet, with a form for choosing two revisions to
+	compare.
+*/
+func (app *application) snippetHistory(w http.ResponseWriter, r *http.Request) {
+	// Get the snippet from the ID in the URL
+	snippet, ok := app.readableSnippet(w, r)
+	if !ok {
+		return
+	}
+
+	// Get the snippet's revisions, newest first
+	revisions, err := app.snippets.Revisions(snippet.ID)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	// Add the snippet and its revisions to the
+	// template data
+	data := app.newTemplateData(r)
+	data.Snippet = snippet
+	data.Revisions = revisions
+
+	// Render the page
+	app.render(w, http.StatusOK, "history.tmpl", data)
+}
+
+/*
+	snippetRevision function displays a single revision
+	of a snippet, determined by the version number in
+	the URL.
+*/
+func (app *application) snippetRevision(w http.ResponseWriter, r *http.Request) {
+	// Get the snippet from the ID in the URL
+	snippet, ok := app.readableSnippet(w, r)
+	if !ok {
+		return
+	}
+
+	// Get the version number from the URL parameters
+	// and validate it as an integer
+	params := httprouter.ParamsFromContext(r.Context())
+	version, err := strconv.Atoi(params.ByName("version"))
+	if err != nil || version < 1 {
+		app.notFound(w)
+		return
+	}
+
+	// Get the revision. Return 404 if not found.
+	revision, err := app.snippets.Revision(snippet.ID, version)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, err)
+		}
+		return
+	}
+
+	// Add the snippet and revision to the template data
+	data := app.newTemplateData(r)
+	data.Snippet = snippet
+	data.Revision = revision
+
+	// Render the page
+	app.render(w, http.StatusOK, "revision.tmpl", data)
+}
+
+/*
+	snippetDiff function displays the line by line
+	differences between two revisions of a snippet. The
+	revisions are chosen with the "from" and "to" query
+	string parameters, which default to the latest
+	revision and the one before it. Setting the "mode"
+	parameter to "split" shows the revisions side by
+	side instead of as a unified diff.
+*/
+func (app *application) snippetDiff(w http.ResponseWriter, r *http.Request) {
+	// Get the snippet from the ID in the URL
+	snippet, ok := app.readableSnippet(w, r)
+	if !ok {
+		return
+	}
+
+	// Get the snippet's revisions, newest first
+	revisions, err := app.snippets.Revisions(snippet.ID)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+	if len(revisions) == 0 {
+		app.notFound(w)
+		return
+	}
+
+	// Work out which revisions to compare. Missing
+	// parameters fall back to the defaults, and anything
+	// else that isn't a number is a bad request.
+	query := r.URL.Query()
+	to := revisions[0].Version
+	from := to - 1
+
+	if v := query.Get("to"); v != "" {
+		to, err = strconv.Atoi(v)
+		if err != nil {
+			app.clientError(w, http.StatusBadRequest)
+			return
+		}
+		from = to - 1
+	}
+	if v := query.Get("from"); v != "" {
+		from, err = strconv.Atoi(v)
+		if err != nil {
+			app.clientError(w, http.StatusBadRequest)
+			return
+		}
+	}
+
+	// Find the chosen revisions. A snippet with only
+	// one revision is compared against nothing, so every
+	// line shows as added.
+	view := &diffView{Split: query.Get("mode") == "split"}
+	for _, rev := range revisions {
+		if rev.Version == from {
+			view.From = rev
+		}
+		if rev.Version == to {
+			view.To = rev
+		}
+	}
+	if view.To == nil || (view.From == nil && from != 0) {
+		app.notFound(w)
+		return
+	}
+
+	// Compare the contents of the two revisions
+	oldContent := ""
+	if view.From != nil {
+		oldContent = view.From.Content
+	}
+	view.Hunks = diff.Hunks(diff.Lines(oldContent, view.To.Content), 3)
+
+	// Add the snippet and the diff to the template data
+	data := app.newTemplateData(r)
+	data.Snippet = snippet
+	data.Diff = view
+
+	// Render the page
+	app.render(w, http.StatusOK, "diff.tmpl", data)
+}
+
+/*
+	snippetMine function displays all of the snippets
+	owned by the logged in user.
+*/
+func (app *application) snippetMine(w http.ResponseWriter, r *http.Request) {
+	// Get the snippets owned by the authenticated user
+	snippets, err := app.snippets.ByUser(app.authenticatedUserID(r))
 	if err != nil {
 		app.serverError(w, err)
 		return
@@ -29,84 +682,219 @@ func (app *application) home(w http.ResponseWriter, r *http.Request) {
 	data.Snippets = snippets
 
 	// Render the page
-	app.render(w, http.StatusOK, "home.tmpl", data)
+	app.render(w, http.StatusOK, "mine.tmpl", data)
+}
+
+/*
+	snippetCreate function responds to a GET function,
+	processes the form template, and present it to
+	the user.
+*/
+func (app *application) snippetCreate(w http.ResponseWriter, r *http.Request) {
+	// Create a new template set
+	data := app.newTemplateData(r)
+
+	// Initialize a new createSnippetForm instance and
+	// pass it to the template. This can be used to set
+	// any 'initial' values for the form.
+	data.Form = snippetCreateForm{
+		Expires:    app.config.expiry.def,
+		Language:   autoDetect,
+		Visibility: models.VisibilityPublic,
+	}
+
+	// Render the template
+	app.render(w, http.StatusOK, "create.tmpl", data)
+}
+
+// The limits snippetCreateForm checks. The OpenAPI
+// document describes the same limits, so API clients
+// know them.
+const (
+	maxTitleChars = 100
+	maxTags       = 10
+)
+
+/*
+	Define a snippetCreateForm to represent the form data
+	and inherit all the fields and methods of the
+	Validator type. All fields are exported, so they 
+	are capitalized. 
+
+	The struct includes struct tags which which tell the
+	form decoder how to map HTML form values into struct
+	fields. Tags holds the tags as typed, separated by
+	commas. Language holds the key of one of languages,
+	or autoDetect. Encrypted is set for snippets which
+	the browser encrypts before the form is sent.
+	Expires holds the key of one of expiryOptions, and
+	ExpiresAt an exact date and time to use instead.
+*/
+type snippetCreateForm struct {
+	Title								string	`form:"title"`
+	Content 						string	`form:"content"`
+	Tags								string	`form:"tags"`
+	Language						string	`form:"language"`
+	Visibility					string	`form:"visibility"`
+	Burn								bool		`form:"burn"`
+	Encrypted						bool		`form:"encrypted"`
+	Password						string	`form:"password"`
+	Expires 						string	`form:"expires"`
+	ExpiresAt						string	`form:"expires_at"`
+	validator.Validator					`form:"-"`
+}
+
+/*
+	checkTitleAndContent function validates the title and
+	content fields of the form. These checks are shared
+	by the create and edit snippet forms.
+*/
+func (form *snippetCreateForm) checkTitleAndContent() {
+	// Check for blank title
+	form.CheckField(validator.NotBlank(form.Title), "title", "This field cannot be blank")
+
+	// Check title for max length
+	form.CheckField(
+		validator.MaxChars(form.Title, maxTitleChars),
+		"title",
+		fmt.Sprintf("This field cannot be more than %d characters long", maxTitleChars))
+
+	// Check for blank content
+	form.CheckField(
+		validator.NotBlank(form.Content),
+		"content",
+		"This field cannot be blank")
+}
+
+/*
+	checkExpires function validates the expires and
+	expires_at fields of the form against the server's
+	expiry settings, and returns when the snippet
+	expires. On the edit snippet form both fields can be
+	left empty to keep the current expiry date, which
+	returns the zero time.
+*/
+func (form *snippetCreateForm) checkExpires(limits expiryConfig, editing bool) time.Time {
+	if editing && form.Expires == "" && form.ExpiresAt == "" {
+		return time.Time{}
+	}
+
+	expires, message := limits.expires(form.Expires, form.ExpiresAt, time.Now().UTC())
+	form.CheckField(message == "", "expires", message)
+
+	return expires
+}
+
+/*
+	checkLanguage function validates the language field
+	of the form, and returns the key of the language the
+	snippet is written in, detecting it from the content
+	if autoDetect was chosen. These checks are shared by
+	the create and edit snippet forms.
+*/
+func (form *snippetCreateForm) checkLanguage() string {
+	// Check the language is one of the languages offered
+	form.CheckField(
+		validator.PermittedValue(form.Language, append(languageKeys(), autoDetect)...),
+		"language",
+		"This field must be one of the languages listed")
+
+	// Encrypted content can't be highlighted, or its
+	// language detected
+	if form.Encrypted {
+		return plainText
+	}
+
+	if form.Language == autoDetect {
+		return detectLanguage(form.Content)
+	}
+
+	return form.Language
 }
 
+// The sizes, in bytes, of the AES-GCM nonce and
+// authentication tag in an encrypted snippet's content.
+const (
+	encryptedNonceSize = 12
+	encryptedTagSize   = 16
+)
+
 /*
-	snippetView function handles a single snippet page
-	determined by snippet ID, ID is stored in the request
-	context. Retrieve ID using the ParseFromContext(),
-	which returns a slice of parameter names and values.
+	checkEncrypted function validates the content of an
+	encrypted snippet, which must be the blob made by
+	ui/static/js/encrypt.js: the base64 encoding of an
+	AES-GCM nonce followed by the ciphertext. Without
+	the key the server can only check its shape. Content
+	which isn't a blob wasn't encrypted, usually because
+	JavaScript is turned off, and is refused rather than
+	stored as plain text. These checks are shared by the
+	create and edit snippet forms.
 */
-func (app *application) snippetView(w http.ResponseWriter, r *http.Request) {
-	// Get request paramters
-	params := httprouter.ParamsFromContext(r.Context())
-
-	// Get id from URL parameters query string
-	// and validate the id as an integer
-	id, err := strconv.Atoi(params.ByName("id"))
-	if err != nil || id < 1 {
-		app.notFound(w)
-		return
-	}
-
-	// Use SnippetModel's GET method to retrieve data
-	// for a record by ID. Return 404 if not found.
-	snippet, err := app.snippets.Get(id)
-	if err != nil {
-		if errors.Is(err, models.ErrNoRecord) {
-			app.notFound(w)
-		} else {
-			app.serverError(w, err)
-		}
+func (form *snippetCreateForm) checkEncrypted() {
+	if !form.Encrypted || !validator.NotBlank(form.Content) {
 		return
 	}
 
-	// Call the newTemplateData()helper to get a
-	// templateData struct containing the default
-	// data and add the snippets slice to it.
-	data := app.newTemplateData(r)
-	data.Snippet = snippet
+	blob, err := base64.StdEncoding.DecodeString(form.Content)
+	form.CheckField(
+		err == nil && len(blob) >= encryptedNonceSize+encryptedTagSize,
+		"content",
+		"Encrypted snippets are encrypted by your browser, which needs JavaScript turned on")
+}
 
-	// Render the page
-	app.render(w, http.StatusOK, "view.tmpl", data)
+/*
+	checkPassword function validates the optional
+	password field of the form, which is only on the
+	create snippet form.
+*/
+func (form *snippetCreateForm) checkPassword() {
+	if form.Password != "" {
+		form.CheckField(
+			validator.MinChars(form.Password, 8),
+			"password",
+			"This field must be at least 8 characters long")
+	}
 }
 
 /*
-	snippetCreate function responds to a GET function,
-	processes the form template, and present it to
-	the user.
+	checkVisibility function validates the visibility
+	field of the form. These checks are shared by the
+	create and edit snippet forms. Burn after reading
+	snippets are never listed, where anyone could burn
+	them, so public ones are made unlisted.
 */
-func (app *application) snippetCreate(w http.ResponseWriter, r *http.Request) {
-	// Create a new template set
-	data := app.newTemplateData(r)
+func (form *snippetCreateForm) checkVisibility() {
+	form.CheckField(
+		validator.PermittedValue(form.Visibility, models.Visibilities...),
+		"visibility",
+		"This field must equal public, unlisted or private")
 
-	// Initialize a new createSnippetForm instance and
-	// pass it to the template. This can be used to set
-	// any 'initial' values for the form.
-	data.Form = snippetCreateForm{
-		Expires: 365,
+	if form.Burn && form.Visibility == models.VisibilityPublic {
+		form.Visibility = models.VisibilityUnlisted
 	}
-
-	// Render the template
-	app.render(w, http.StatusOK, "create.tmpl", data)
 }
 
 /*
-	Define a snippetCreateForm to represent the form data
-	and inherit all the fields and methods of the
-	Validator type. All fields are exported, so they 
-	are capitalized. 
-
-	The struct includes struct tags which which tell the
-	form decoder how to map HTML form values into struct
-	fields.
+	checkTags function validates the tags field of the
+	form, and returns the tags it holds. These checks
+	are shared by the create and edit snippet forms.
 */
-type snippetCreateForm struct {
-	Title								string	`form:"title"`
-	Content 						string	`form:"content"`
-	Expires 						int			`form:"expires"`
-	validator.Validator					`form:"-"`
+func (form *snippetCreateForm) checkTags() []string {
+	tags := parseTags(form.Tags)
+
+	// Check for too many tags
+	form.CheckField(
+		validator.MaxItems(tags, maxTags),
+		"tags",
+		fmt.Sprintf("There cannot be more than %d tags", maxTags))
+
+	// Check each tag is made of allowed characters
+	form.CheckField(
+		validator.AllMatch(tags, validator.TagRX),
+		"tags",
+		"Tags can only contain letters, numbers and + # . _ - and be up to 30 characters long")
+
+	return tags
 }
 
 /*
@@ -129,25 +917,15 @@ func (app *application) snippetCreatePost(w http.ResponseWriter, r *http.Request
 
 	// BEGIN VALIDATION
 
-	// Check for blank title
-	 form.CheckField(validator.NotBlank(form.Title), "title", "This field cannot be blank")
-
-	// Check title for max length
-	form.CheckField(
-		validator.MaxChars(form.Title, 100),
-		"title",
-		"This field cannot be more than 100 characters long")
-	
-	// Check for blank content
-	form.CheckField(
-		validator.NotBlank(form.Content),
-		"content",
-		"This field cannot be blank")
-
-	form.CheckField(
-		validator.PermittedInt(form.Expires, 1, 7, 365),
-		"expires",
-		"This field must equal 1, 7, or 365")
+	// Check the title, content, language, visibility,
+	// password, tags and expiry fields
+	form.checkTitleAndContent()
+	form.checkEncrypted()
+	language := form.checkLanguage()
+	form.checkVisibility()
+	form.checkPassword()
+	tags := form.checkTags()
+	expires := form.checkExpires(app.config.expiry, false)
 
 	// Use Valid() method to check for any validation
 	// fails. If so, re-render the template, passing in
@@ -161,9 +939,10 @@ func (app *application) snippetCreatePost(w http.ResponseWriter, r *http.Request
  
 	// ADD TO DATABASE
 
-	// Pass data to SnippetModel.Insert() method.
-	// The ID is returned
-	id, err := app.snippets.Insert(form.Title, form.Content, form.Expires)
+	// Pass data to SnippetModel.Insert() method, along
+	// with the ID of the logged in user who owns the
+	// snippet. The slug of the new snippet is returned
+	slug, err := app.snippets.Insert(form.Title, form.Content, language, form.Visibility, form.Burn, form.Encrypted, form.Password, tags, expires, app.authenticatedUserID(r))
 	if err != nil {
 		app.serverError(w, err)
 		return
@@ -177,7 +956,215 @@ func (app *application) snippetCreatePost(w http.ResponseWriter, r *http.Request
 	)
 	
 	// Redirect user to new snippet page
-	http.Redirect(w, r, fmt.Sprintf("/snippet/view/%d", id), http.StatusSeeOther)
+	http.Redirect(w, r, "/snippet/view/"+slug, http.StatusSeeOther)
+}
+
+/*
+	ownedSnippet function gets the snippet whose ID is in
+	the URL parameters and checks it belongs to the logged
+	in user. If the snippet doesn't exist a 404 Not Found
+	response is sent, and if it belongs to someone else a
+	403 Forbidden response is sent. In both cases false is
+	returned and the caller should stop handling the
+	request.
+*/
+func (app *application) ownedSnippet(w http.ResponseWriter, r *http.Request) (*models.Snippet, bool) {
+	// Retrieve the snippet. Return 404 if not found.
+	snippet, ok := app.snippetFromURL(w, r)
+	if !ok {
+		return nil, false
+	}
+
+	// Only the user who created the snippet may change it
+	if !snippet.OwnedBy(app.authenticatedUserID(r)) {
+		app.clientError(w, http.StatusForbidden)
+		return nil, false
+	}
+
+	return snippet, true
+}
+
+/*
+	snippetEdit function displays the form for editing
+	an existing snippet, filled in with its current
+	title, content, language and tags. The expiry is
+	left empty, which keeps the current expiry date.
+*/
+func (app *application) snippetEdit(w http.ResponseWriter, r *http.Request) {
+	// Get the snippet, checking the user owns it
+	snippet, ok := app.ownedSnippet(w, r)
+	if !ok {
+		return
+	}
+
+	// Create a new template set and fill the form
+	// with the snippet's current values
+	data := app.newTemplateData(r)
+	data.Snippet = snippet
+	data.Form = snippetCreateForm{
+		Title:    snippet.Title,
+		Content:  snippet.Content,
+		Tags:       strings.Join(snippet.Tags, ", "),
+		Language:   snippet.Language,
+		Visibility: snippet.Visibility,
+		Burn:       snippet.Burn,
+		Encrypted:  snippet.Encrypted,
+	}
+
+	// Render the template
+	app.render(w, http.StatusOK, "edit.tmpl", data)
+}
+
+/*
+	snippetEditPost function handles updating an
+	existing snippet. The form is validated in the same
+	way as a new snippet, except the expiry can be left
+	empty to keep the current expiry date. A new expiry
+	counts from now, so it can make the snippet last
+	longer or expire sooner.
+*/
+func (app *application) snippetEditPost(w http.ResponseWriter, r *http.Request) {
+	// Get the snippet, checking the user owns it
+	snippet, ok := app.ownedSnippet(w, r)
+	if !ok {
+		return
+	}
+
+	// Decode the form data into a snippetCreateForm
+	var form snippetCreateForm
+
+	err := app.decodePostForm(r, &form)
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	// Whether the snippet burns after reading or is
+	// encrypted can't be changed
+	form.Burn = snippet.Burn
+	form.Encrypted = snippet.Encrypted
+
+	// Check the title, content, language, visibility,
+	// tags and expiry fields and re-render the form if
+	// there are any errors
+	form.checkTitleAndContent()
+	form.checkEncrypted()
+	language := form.checkLanguage()
+	form.checkVisibility()
+	tags := form.checkTags()
+	expires := form.checkExpires(app.config.expiry, true)
+
+	if !form.Valid() {
+		data := app.newTemplateData(r)
+		data.Snippet = snippet
+		data.Form = form
+		app.render(w, http.StatusUnprocessableEntity, "edit.tmpl", data)
+		return
+	}
+
+	// Save the changes to the database
+	err = app.snippets.Update(snippet.ID, form.Title, form.Content, language, form.Visibility, tags, expires, app.authenticatedUserID(r))
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	// Create a session value for a flash message to user
+	app.sessionManager.Put(
+		r.Context(),
+		"flash",
+		"Snippet successfully updated!",
+	)
+
+	// Redirect user back to the snippet page
+	http.Redirect(w, r, "/snippet/view/"+snippet.Slug, http.StatusSeeOther)
+}
+
+/*
+	snippetDeletePost function moves a snippet owned by
+	the logged in user to their trash.
+*/
+func (app *application) snippetDeletePost(w http.ResponseWriter, r *http.Request) {
+	// Get the snippet, checking the user owns it
+	snippet, ok := app.ownedSnippet(w, r)
+	if !ok {
+		return
+	}
+
+	// Move the snippet to the trash
+	err := app.snippets.Delete(snippet.ID)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	// Create a session value for a flash message to user
+	app.sessionManager.Put(
+		r.Context(),
+		"flash",
+		"Snippet moved to the trash",
+	)
+
+	// Redirect user to their trash
+	http.Redirect(w, r, "/snippet/trash", http.StatusSeeOther)
+}
+
+/*
+	snippetTrash function displays the logged in user's
+	deleted snippets which can still be restored.
+	Snippets which have been in the trash too long are
+	left out rather than purged here, so viewing the
+	page never deletes anything.
+*/
+func (app *application) snippetTrash(w http.ResponseWriter, r *http.Request) {
+	// Get the snippets in the authenticated user's trash
+	snippets, err := app.snippets.Trash(app.authenticatedUserID(r))
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	// Call the newTemplateData()helper to get a
+	// templateData struct containing the default
+	// data and add the snippets slice to it.
+	data := app.newTemplateData(r)
+	data.Snippets = snippets
+
+	// Render the page
+	app.render(w, http.StatusOK, "trash.tmpl", data)
+}
+
+/*
+	snippetRestorePost function takes a snippet back out
+	of the logged in user's trash.
+*/
+func (app *application) snippetRestorePost(w http.ResponseWriter, r *http.Request) {
+	// Get request paramters
+	params := httprouter.ParamsFromContext(r.Context())
+	slug := params.ByName("slug")
+
+	// Restore the snippet. Only snippets in the user's
+	// own trash can be restored, anything else is
+	// reported as not found.
+	err := app.snippets.Restore(slug, app.authenticatedUserID(r))
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, err)
+		}
+		return
+	}
+
+	// Create a session value for a flash message to user
+	app.sessionManager.Put(
+		r.Context(),
+		"flash",
+		"Snippet successfully restored!",
+	)
+
+	// Redirect user to the restored snippet page
+	http.Redirect(w, r, "/snippet/view/"+slug, http.StatusSeeOther)
 }
 
 // userSignupForm struct takes in the form values from
@@ -397,4 +1384,116 @@ func (app *application) userLogoutPost(w http.ResponseWriter, r *http.Request) {
 
 	// Redirect user to application home page
 	http.Redirect(w, r, "/", http.StatusSeeOther)
-}
\ No newline at end of file
+}
+// Create a tokenCreateForm struct for the form creating
+// an API token on the settings page
+type tokenCreateForm struct {
+	Name								string	`form:"name"`
+	Scope								string	`form:"scope"`
+	validator.Validator					`form:"-"`
+}
+
+/*
+	userSettings displays the logged in user's settings
+	page, which lists their API tokens and has a form to
+	create a new one.
+*/
+func (app *application) userSettings(w http.ResponseWriter, r *http.Request) {
+	data := app.newTemplateData(r)
+	data.Form = tokenCreateForm{Scope: models.ScopeRead}
+
+	app.renderSettings(w, r, http.StatusOK, data)
+}
+
+/*
+	renderSettings function adds the logged in user's
+	API tokens to data and renders the settings page.
+	A token which has just been created is shown once,
+	straight after it is created.
+*/
+func (app *application) renderSettings(w http.ResponseWriter, r *http.Request, status int, data *templateData) {
+	tokens, err := app.users.Tokens(app.authenticatedUserID(r))
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	data.Tokens = tokens
+	data.NewToken = app.sessionManager.PopString(r.Context(), "newToken")
+
+	app.render(w, status, "settings.tmpl", data)
+}
+
+/*
+	userTokenCreatePost creates a new API token for the
+	logged in user.
+*/
+func (app *application) userTokenCreatePost(w http.ResponseWriter, r *http.Request) {
+	var form tokenCreateForm
+
+	err := app.decodePostForm(r, &form)
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	// Validate input
+	form.CheckField(validator.NotBlank(form.Name), "name", "This field cannot be blank")
+	form.CheckField(
+		validator.MaxChars(form.Name, 50),
+		"name",
+		"This field cannot be more than 50 characters long")
+	form.CheckField(
+		validator.PermittedValue(form.Scope, models.ScopeRead, models.ScopeWrite),
+		"scope",
+		"This field must equal read or write")
+
+	if !form.Valid() {
+		data := app.newTemplateData(r)
+		data.Form = form
+		app.renderSettings(w, r, http.StatusUnprocessableEntity, data)
+		return
+	}
+
+	token, err := app.users.InsertToken(app.authenticatedUserID(r), form.Name, form.Scope)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	// Only the token's hash is stored, so this is the
+	// only chance to show the token. Keep it in the
+	// session just until the settings page shows it.
+	app.sessionManager.Put(r.Context(), "newToken", token)
+
+	http.Redirect(w, r, "/user/settings", http.StatusSeeOther)
+}
+
+/*
+	userTokenRevokePost deletes one of the logged in
+	user's API tokens, so it can no longer be used.
+*/
+func (app *application) userTokenRevokePost(w http.ResponseWriter, r *http.Request) {
+	params := httprouter.ParamsFromContext(r.Context())
+
+	id, err := strconv.Atoi(params.ByName("id"))
+	if err != nil || id < 1 {
+		app.notFound(w)
+		return
+	}
+
+	// Only the user who owns the token can revoke it
+	err = app.users.RevokeToken(id, app.authenticatedUserID(r))
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, err)
+		}
+		return
+	}
+
+	app.sessionManager.Put(r.Context(), "flash", "API token revoked")
+
+	http.Redirect(w, r, "/user/settings", http.StatusSeeOther)
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/robwestbrook/snippetbox/internal/models"
+)
+
+func TestSnippetViewVisibility(t *testing.T) {
+	app := newTestApplication(t)
+	owner := newTestUser(t, app, "owner")
+	newTestUser(t, app, "other")
+
+	slugs := map[string]string{}
+	for _, visibility := range models.Visibilities {
+		slug, err := app.snippets.Insert("A "+visibility+" snippet", "Its content", "text", visibility, false, false, "", nil, time.Now().Add(time.Hour), owner)
+		if err != nil {
+			t.Fatal(err)
+		}
+		slugs[visibility] = slug
+	}
+
+	// Each user has their own client, so their own
+	// session. An empty user isn't logged in.
+	tests := []struct {
+		name				string
+		user				string
+		visibility	string
+		wantCode		int
+	}{
+		{"Public to anyone", "", models.VisibilityPublic, http.StatusOK},
+		{"Unlisted to anyone", "", models.VisibilityUnlisted, http.StatusOK},
+		{"Private to anyone", "", models.VisibilityPrivate, http.StatusNotFound},
+		{"Private to another user", "other", models.VisibilityPrivate, http.StatusNotFound},
+		{"Private to its owner", "owner", models.VisibilityPrivate, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := newTestServer(t, app.routes())
+			if tt.user != "" {
+				ts.login(t, tt.user+"@example.com")
+			}
+
+			// The page and the raw content are hidden alike
+			for _, path := range []string{"/snippet/view/", "/snippet/raw/"} {
+				code, _, _ := ts.get(t, path+slugs[tt.visibility])
+				if code != tt.wantCode {
+					t.Errorf("got status %d for %s, want %d", code, path, tt.wantCode)
+				}
+			}
+		})
+	}
+}
+
+func TestSnippetBurn(t *testing.T) {
+	app := newTestApplication(t)
+	owner := newTestUser(t, app, "owner")
+
+	slug, err := app.snippets.Insert("A burn after reading snippet", "Its content", "text", models.VisibilityPublic, true, false, "", nil, time.Now().Add(time.Hour), owner)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The owner can view the snippet as often as they
+	// like without burning it
+	ownerServer := newTestServer(t, app.routes())
+	ownerServer.login(t, "owner@example.com")
+	for i := 0; i < 2; i++ {
+		code, _, body := ownerServer.get(t, "/snippet/view/"+slug)
+		if code != http.StatusOK || !strings.Contains(body, "Its content") {
+			t.Fatalf("got status %d on the owner's view %d, want the snippet", code, i+1)
+		}
+	}
+
+	ts := newTestServer(t, app.routes())
+
+	// Viewing the snippet, or fetching it from the API,
+	// only asks whether to reveal it
+	code, _, body := ts.get(t, "/snippet/view/"+slug)
+	if code != http.StatusOK || strings.Contains(body, "Its content") {
+		t.Errorf("got status %d, want the reveal page without the content", code)
+	}
+	code, _, body = ts.get(t, "/api/v1/snippets/"+slug)
+	if code != http.StatusOK || strings.Contains(body, "Its content") {
+		t.Errorf("got status %d from the API, want the snippet without its content", code)
+	}
+
+	// Revealing it shows it once
+	code, _, body = ts.postForm(t, "/snippet/reveal/"+slug, "/snippet/view/"+slug, url.Values{})
+	if code != http.StatusOK || !strings.Contains(body, "Its content") {
+		t.Errorf("got status %d revealing it, want the snippet", code)
+	}
+
+	code, _, _ = ts.get(t, "/snippet/view/"+slug)
+	if code != http.StatusNotFound {
+		t.Errorf("got status %d after revealing it, want %d", code, http.StatusNotFound)
+	}
+	code, _, _ = ts.do(t, http.MethodPost, "/api/v1/snippets/"+slug+"/reveal", nil, nil)
+	if code != http.StatusNotFound {
+		t.Errorf("got status %d revealing it again from the API, want %d", code, http.StatusNotFound)
+	}
+}
+
+func TestSnippetUnlock(t *testing.T) {
+	app := newTestApplication(t)
+	owner := newTestUser(t, app, "owner")
+
+	// Two snippets with the same password
+	first, err := app.snippets.Insert("A locked snippet", "Its content", "text", models.VisibilityPublic, false, false, "pa55word", nil, time.Now().Add(time.Hour), owner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := app.snippets.Insert("Another locked snippet", "Its content", "text", models.VisibilityPublic, false, false, "pa55word", nil, time.Now().Add(time.Hour), owner)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ts := newTestServer(t, app.routes())
+
+	unlock := func(slug, password string) int {
+		code, _, _ := ts.postForm(t, "/snippet/unlock/"+slug, "/snippet/view/"+slug, url.Values{
+			"password": {password},
+		})
+		return code
+	}
+
+	// Locked snippets ask for the password
+	code, _, body := ts.get(t, "/snippet/view/"+first)
+	if code != http.StatusOK || strings.Contains(body, "Its content") {
+		t.Errorf("got status %d, want the unlock page without the content", code)
+	}
+
+	code = unlock(first, "wrong")
+	if code != http.StatusUnprocessableEntity {
+		t.Errorf("got status %d with the wrong password, want %d", code, http.StatusUnprocessableEntity)
+	}
+	code, _, _ = ts.get(t, "/snippet/raw/"+first)
+	if code != http.StatusSeeOther {
+		t.Errorf("got status %d after the wrong password, want %d", code, http.StatusSeeOther)
+	}
+
+	code = unlock(first, "pa55word")
+	if code != http.StatusSeeOther {
+		t.Errorf("got status %d with the correct password, want %d", code, http.StatusSeeOther)
+	}
+	code, _, body = ts.get(t, "/snippet/raw/"+first)
+	if code != http.StatusOK || body != "Its content" {
+		t.Errorf("got status %d and %q after unlocking, want the content", code, body)
+	}
+
+	// Unlocking one snippet doesn't unlock another, even
+	// with the same password, nor for another session
+	code, _, _ = ts.get(t, "/snippet/raw/"+second)
+	if code != http.StatusSeeOther {
+		t.Errorf("got status %d for another snippet, want %d", code, http.StatusSeeOther)
+	}
+	code, _, _ = newTestServer(t, app.routes()).get(t, "/snippet/raw/"+first)
+	if code != http.StatusSeeOther {
+		t.Errorf("got status %d for another session, want %d", code, http.StatusSeeOther)
+	}
+}
+
+func TestSnippetUnlockLimit(t *testing.T) {
+	app := newTestApplication(t)
+	app.unlocks = newAttemptLimiter(2, time.Minute)
+	owner := newTestUser(t, app, "owner")
+
+	slug, err := app.snippets.Insert("A locked snippet", "Its content", "text", models.VisibilityPublic, false, false, "pa55word", nil, time.Now().Add(time.Hour), owner)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ts := newTestServer(t, app.routes())
+
+	unlock := func(password string) (int, http.Header) {
+		code, header, _ := ts.postForm(t, "/snippet/unlock/"+slug, "/snippet/view/"+slug, url.Values{
+			"password": {password},
+		})
+		return code, header
+	}
+
+	for i := 0; i < 2; i++ {
+		code, _ := unlock("wrong")
+		if code != http.StatusUnprocessableEntity {
+			t.Fatalf("got status %d for wrong password %d, want %d", code, i+1, http.StatusUnprocessableEntity)
+		}
+	}
+
+	// Once over the limit, even the correct password has
+	// to wait, from the web and from the API alike
+	code, header := unlock("pa55word")
+	if code != http.StatusTooManyRequests || header.Get("Retry-After") == "" {
+		t.Errorf("got status %d and Retry-After %q over the limit, want %d with a Retry-After", code, header.Get("Retry-After"), http.StatusTooManyRequests)
+	}
+
+	code, _, _ = ts.do(t, http.MethodGet, "/api/v1/snippets/"+slug, nil, http.Header{"X-Snippet-Password": {"pa55word"}})
+	if code != http.StatusTooManyRequests {
+		t.Errorf("got status %d from the API over the limit, want %d", code, http.StatusTooManyRequests)
+	}
+}
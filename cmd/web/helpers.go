@@ -6,10 +6,12 @@ import (
 	"fmt"
 	"net/http"
 	"runtime/debug"
+	"strings"
 	"time"
 
 	"github.com/go-playground/form/v4"
 	"github.com/justinas/nosurf"
+	"github.com/robwestbrook/snippetbox/internal/models"
 )
 
 // ServerError helper.
@@ -51,6 +53,7 @@ func (app *application) newTemplateData(r *http.Request) *templateData {
 		Flash: 						app.sessionManager.PopString(r.Context(), "flash"),
 		IsAuthenticated: 	app.isAuthenticated(r),
 		CSRFToken: 				nosurf.Token(r),
+		Expiries:					app.config.expiry.choices(),
 	}
 }
 
@@ -129,4 +132,101 @@ func (app *application) isAuthenticated(r *http.Request) bool {
 	}
 
 	return isAuthenticated
-}
\ No newline at end of file
+}
+
+// authenticatedUserID function returns the ID of the
+// user the authenticate or apiAuthenticate middleware
+// found for the current request, or 0 if the request
+// isn't from an authenticated user.
+func (app *application) authenticatedUserID(r *http.Request) int {
+	id, ok := r.Context().Value(authenticatedUserIDContextKey).(int)
+
+	if !ok {
+		return 0
+	}
+
+	return id
+}
+// parseTags function splits the comma separated tags
+// typed into a form into a slice. Tags are trimmed and
+// lowercased, and blank or repeated tags are dropped,
+// so "Go, sql,,go" gives "go" and "sql".
+func parseTags(s string) []string {
+	tags := []string{}
+	seen := map[string]bool{}
+
+	for _, tag := range strings.Split(s, ",") {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		tags = append(tags, tag)
+	}
+
+	return tags
+}
+
+// absoluteURL function returns the full URL of a path
+// on this server, using the host and scheme the request
+// was made with.
+func (app *application) absoluteURL(r *http.Request, path string) string {
+	scheme := "https"
+	if !isSecure(r) {
+		scheme = "http"
+	}
+	return scheme + "://" + r.Host + path
+}
+
+// downloadName function returns the file name a
+// snippet is downloaded as: its title in lowercase with
+// runs of anything but letters and numbers turned into
+// dashes, followed by the file extension of its
+// language. Snippets without a usable title are named
+// after their ID.
+func downloadName(snippet *models.Snippet) string {
+	var b strings.Builder
+	dash := false
+
+	for _, c := range strings.ToLower(snippet.Title) {
+		if (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') {
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(c)
+			dash = false
+		} else {
+			dash = true
+		}
+
+		if b.Len() >= 50 {
+			break
+		}
+	}
+
+	name := b.String()
+	if name == "" {
+		name = fmt.Sprintf("snippet-%d", snippet.ID)
+	}
+
+	return name + lookupLanguage(snippet.Language).Ext
+}
+
+// unlockKey function returns the session key which
+// records that the snippet's password has been entered.
+// Each snippet has its own key, so unlocking one snippet
+// doesn't unlock any others.
+func unlockKey(snippet *models.Snippet) string {
+	return "unlocked:" + snippet.Slug
+}
+
+// isUnlocked function returns true if the snippet can
+// be shown to the current user: it has no password, the
+// user owns it, or its password has been entered in
+// this session.
+func (app *application) isUnlocked(r *http.Request, snippet *models.Snippet) bool {
+	if !snippet.Protected || snippet.OwnedBy(app.authenticatedUserID(r)) {
+		return true
+	}
+	return app.sessionManager.GetBool(r.Context(), unlockKey(snippet))
+}
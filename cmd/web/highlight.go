@@ -0,0 +1,172 @@
+package main
+
+import (
+	"bytes"
+	"html/template"
+	"net/http"
+
+	"github.com/alecthomas/chroma/v2"
+	"github.com/alecthomas/chroma/v2/formatters/html"
+	"github.com/alecthomas/chroma/v2/lexers"
+	"github.com/alecthomas/chroma/v2/styles"
+)
+
+// language defines a type to hold a programming
+// language a snippet can be written in.
+// Contains:
+//	1. Key - stored with the snippet, and the name of
+//					 the chroma lexer which highlights it
+//	2. Name - shown to users
+//	3. Ext - the file extension for the language
+type language struct {
+	Key		string
+	Name	string
+	Ext		string
+}
+
+// plainText is the key of the language for snippets
+// which aren't highlighted.
+const plainText = "text"
+
+// autoDetect is the value of the language form field
+// when the language should be worked out from the
+// snippet's content.
+const autoDetect = "auto"
+
+// languages lists the languages offered on the snippet
+// forms, plain text first.
+var languages = []language{
+	{Key: plainText, Name: "Plain text", Ext: ".txt"},
+	{Key: "bash", Name: "Bash", Ext: ".sh"},
+	{Key: "c", Name: "C", Ext: ".c"},
+	{Key: "cpp", Name: "C++", Ext: ".cpp"},
+	{Key: "css", Name: "CSS", Ext: ".css"},
+	{Key: "docker", Name: "Dockerfile", Ext: ".dockerfile"},
+	{Key: "go", Name: "Go", Ext: ".go"},
+	{Key: "html", Name: "HTML", Ext: ".html"},
+	{Key: "java", Name: "Java", Ext: ".java"},
+	{Key: "javascript", Name: "JavaScript", Ext: ".js"},
+	{Key: "json", Name: "JSON", Ext: ".json"},
+	{Key: "markdown", Name: "Markdown", Ext: ".md"},
+	{Key: "php", Name: "PHP", Ext: ".php"},
+	{Key: "python", Name: "Python", Ext: ".py"},
+	{Key: "ruby", Name: "Ruby", Ext: ".rb"},
+	{Key: "rust", Name: "Rust", Ext: ".rs"},
+	{Key: "sql", Name: "SQL", Ext: ".sql"},
+	{Key: "typescript", Name: "TypeScript", Ext: ".ts"},
+	{Key: "yaml", Name: "YAML", Ext: ".yaml"},
+}
+
+/*
+	languageKeys function returns the key of every
+	language in languages.
+*/
+func languageKeys() []string {
+	keys := make([]string, len(languages))
+	for i, l := range languages {
+		keys[i] = l.Key
+	}
+	return keys
+}
+
+/*
+	lookupLanguage function returns the language with
+	the given key, or plain text if there isn't one.
+*/
+func lookupLanguage(key string) language {
+	for _, l := range languages {
+		if l.Key == key {
+			return l
+		}
+	}
+	return languages[0]
+}
+
+/*
+	detectLanguage function guesses which of languages
+	content is written in, using the analysers of the
+	chroma lexers, and returns its key. Plain text is
+	returned if no language looks likely.
+*/
+func detectLanguage(content string) string {
+	best, bestScore := plainText, float32(0)
+
+	for _, l := range languages[1:] {
+		lexer := lexers.Get(l.Key)
+		if lexer == nil {
+			continue
+		}
+
+		if score := lexer.AnalyseText(content); score > bestScore {
+			best, bestScore = l.Key, score
+		}
+	}
+
+	return best
+}
+
+// highlightStyle is the chroma style snippets are
+// highlighted with.
+var highlightStyle = styles.Get("github")
+
+// highlightFormatter writes highlighted code as HTML
+// using classes rather than inline styles, which the
+// Content-Security-Policy set by secureHeaders would
+// block. The styles for the classes are served by the
+// highlightCSS handler. Line numbers go in a separate
+// table column, so they aren't copied with the code.
+var highlightFormatter = html.New(
+	html.WithClasses(true),
+	html.WithLineNumbers(true),
+	html.LineNumbersInTable(true),
+)
+
+/*
+	highlightCode function returns content as HTML,
+	highlighted as the language with the given key.
+	Special characters in content are escaped by the
+	formatter.
+*/
+func highlightCode(key string, content string) (template.HTML, error) {
+	lexer := lexers.Get(key)
+	if lexer == nil || key == plainText {
+		lexer = lexers.Fallback
+	}
+
+	// Merge runs of tokens of the same type, so there
+	// are fewer elements in the HTML
+	lexer = chroma.Coalesce(lexer)
+
+	iterator, err := lexer.Tokenise(nil, content)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+
+	err = highlightFormatter.Format(&buf, highlightStyle, iterator)
+	if err != nil {
+		return "", err
+	}
+
+	return template.HTML(buf.String()), nil
+}
+
+/*
+	highlightCSS function serves the stylesheet for the
+	classes in highlighted code.
+*/
+func (app *application) highlightCSS(w http.ResponseWriter, r *http.Request) {
+	// Write the stylesheet to a buffer first, so an error
+	// can still be sent as a 500 response
+	var buf bytes.Buffer
+
+	err := highlightFormatter.WriteCSS(&buf, highlightStyle)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/css; charset=utf-8")
+	buf.WriteTo(w)
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// The number of wrong passwords a client can enter for
+// snippets in each unlockWindow before it has to wait.
+const (
+	unlockAttempts = 10
+	unlockWindow   = time.Minute
+)
+
+// attemptLimiter defines a type to count failed
+// attempts by each client in fixed windows of time, so
+// passwords can't be guessed at full speed. It is safe
+// for concurrent use.
+//
+// Fields available:
+//	1. limit - failed attempts allowed in each window
+//	2. window - how long each window lasts
+//	3. clients - the window of each client with failures
+//	4. pruned - when windows which have ended were last removed
+type attemptLimiter struct {
+	mu			sync.Mutex
+	limit		int
+	window	time.Duration
+	clients	map[string]*attemptWindow
+	pruned	time.Time
+}
+
+// attemptWindow defines a type to hold when a client's
+// current window started and how many attempts failed
+// in it.
+type attemptWindow struct {
+	start		time.Time
+	failed	int
+}
+
+/*
+	newAttemptLimiter function returns an attemptLimiter
+	which allows limit failed attempts in each window.
+*/
+func newAttemptLimiter(limit int, window time.Duration) *attemptLimiter {
+	return &attemptLimiter{
+		limit:		limit,
+		window:		window,
+		clients:	make(map[string]*attemptWindow),
+		pruned:		time.Now(),
+	}
+}
+
+/*
+	allow function checks whether the client may make
+	another attempt. If it may not, how long it has to
+	wait until its window ends is returned as well.
+*/
+func (l *attemptLimiter) allow(client string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	l.prune(now)
+
+	w, ok := l.clients[client]
+	if !ok || now.Sub(w.start) >= l.window || w.failed < l.limit {
+		return true, 0
+	}
+
+	return false, w.start.Add(l.window).Sub(now)
+}
+
+/*
+	fail function records a failed attempt by the
+	client, starting a new window if its last one has
+	ended.
+*/
+func (l *attemptLimiter) fail(client string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+
+	w, ok := l.clients[client]
+	if !ok || now.Sub(w.start) >= l.window {
+		w = &attemptWindow{start: now}
+		l.clients[client] = w
+	}
+	w.failed++
+}
+
+/*
+	prune function removes the windows which have ended,
+	at most once a window, so the map doesn't keep
+	growing with every client that has ever failed. The
+	caller must hold the lock.
+*/
+func (l *attemptLimiter) prune(now time.Time) {
+	if now.Sub(l.pruned) < l.window {
+		return
+	}
+
+	for client, w := range l.clients {
+		if now.Sub(w.start) >= l.window {
+			delete(l.clients, client)
+		}
+	}
+	l.pruned = now
+}
+
+/*
+	clientIP function returns the IP address a request
+	came from, which attempts are counted against. For
+	requests through a trusted proxy, realClient has
+	already put the client's address in r.RemoteAddr.
+*/
+func clientIP(r *http.Request) string {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return ip
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAttemptLimiter(t *testing.T) {
+	l := newAttemptLimiter(3, 50*time.Millisecond)
+
+	// Each client has its own count of failures
+	for i := 0; i < 3; i++ {
+		ok, _ := l.allow("192.0.2.1")
+		if !ok {
+			t.Fatalf("got attempt %d refused, want it allowed", i+1)
+		}
+		l.fail("192.0.2.1")
+	}
+
+	ok, wait := l.allow("192.0.2.1")
+	if ok || wait <= 0 || wait > 50*time.Millisecond {
+		t.Errorf("got allowed %t and wait %s after the limit, want refused with a wait of up to 50ms", ok, wait)
+	}
+	ok, _ = l.allow("192.0.2.2")
+	if !ok {
+		t.Error("got another client refused, want it allowed")
+	}
+
+	// Once the window has ended the client can try again,
+	// and its old window is pruned
+	time.Sleep(60 * time.Millisecond)
+
+	ok, _ = l.allow("192.0.2.1")
+	if !ok {
+		t.Error("got refused after the window ended, want allowed")
+	}
+	if len(l.clients) != 0 {
+		t.Errorf("got %d windows kept, want them pruned", len(l.clients))
+	}
+}
@@ -2,19 +2,20 @@
 package main
 
 import (
-	"crypto/tls"
 	"database/sql"
-	"flag"
+	"fmt"
 	"html/template"
 	"log"
 	"net/http"
 	"os"
-	"time"
+	"strings"
+	"sync"
 
 	"github.com/alexedwards/scs/sqlite3store"
 	"github.com/alexedwards/scs/v2"
 	"github.com/go-playground/form/v4"
 	_ "github.com/mattn/go-sqlite3"
+	"github.com/robwestbrook/snippetbox/internal/migrations"
 	"github.com/robwestbrook/snippetbox/internal/models"
 )
 
@@ -31,6 +32,10 @@ import (
 //	5. templateCache - template in-memory cache
 // 	6. formDecoder - decodes all form input
 //	7. sessionManager - manages all user sessions
+//	8. sessions - session model for tidying the session store
+//	9. config - configuration settings
+//	10. background - tracks running background goroutines
+//	11. unlocks - limits wrong snippet passwords from each client
 type application struct {
 	errorLog 				*log.Logger
 	infoLog  				*log.Logger
@@ -39,12 +44,35 @@ type application struct {
 	templateCache		map[string]*template.Template
 	formDecoder			*form.Decoder
 	sessionManager	*scs.SessionManager
+	sessions				*models.SessionModel
+	config					config
+	background			sync.WaitGroup
+	unlocks					*attemptLimiter
 }
 
+// busyTimeout is how long, in milliseconds, a
+// connection waits for another connection to finish
+// writing to the database before failing with
+// "database is locked". Without it, two requests
+// writing at once, like two people burning the same
+// snippet, would make one of them fail.
+const busyTimeout = 5000
+
 // Open DB function
 // Wraps sql.Open() and returns a sql.DB connection pool
-// for the DSN
+// for the DSN. The busy timeout is added to the DSN,
+// unless it already sets one.
 func openDB(dsn string) (*sql.DB, error) {
+	// go-sqlite3 takes the timeout as _busy_timeout or
+	// _timeout, and this matches either
+	if !strings.Contains(dsn, "_timeout=") {
+		sep := "?"
+		if strings.Contains(dsn, "?") {
+			sep = "&"
+		}
+		dsn += fmt.Sprintf("%s_busy_timeout=%d", sep, busyTimeout)
+	}
+
 	db, err := sql.Open("sqlite3", dsn)
 	if err != nil {
 		return nil, err
@@ -61,15 +89,6 @@ func openDB(dsn string) (*sql.DB, error) {
 //  2. Establishing the dependencies for the handlers
 //  3. Running the HTTP server
 func main() {
-	 // Define command line flags
-	// "addr"	: 	http PORT (default: 8000)
-	// "dsn"	:		database DSN string (database name)
-	// Then parse the command line flags.
-	// Read the command line flags and assign to variable
-	addr := flag.String("addr", ":8000", "HTTP network address")
-	dsn := flag.String("dsn", "./snippetbox.db", "SQLite data source file name")
-	flag.Parse()
-
 	// Create a logger for writing information  and
 	// error messages.
 	// Takes 3 paramters:
@@ -80,17 +99,91 @@ func main() {
 	infoLog := log.New(os.Stdout, "INFO\t", log.Ldate|log.Ltime)
 	errorLog := log.New(os.Stderr, "ERROR\t", log.Ldate|log.Ltime|log.Lshortfile)
 
+	// Read the settings from the command line flags, the
+	// SNIPPETBOX_* environment variables and the config
+	// file, and check they make sense.
+	// loadConfig() - cmd/web/config.go
+	cfg, flags, err := loadConfig(os.Args[1:], os.LookupEnv)
+	if err != nil {
+		errorLog.Fatal(err)
+	}
+
+	// If asked to, print the settings and exit before
+	// touching the database
+	if cfg.printConfig {
+		err = writeConfig(os.Stdout, flags)
+		if err != nil {
+			errorLog.Fatal(err)
+		}
+		return
+	}
+
+	// If the "gencert" subcommand was given, write a
+	// self-signed certificate and exit without starting
+	// the server. gencert() - cmd/web/certs.go
+	if flags.Arg(0) == "gencert" {
+		err = gencert(cfg, os.Stdout, flags.Args()[1:])
+		if err != nil {
+			errorLog.Fatal(err)
+		}
+		return
+	}
+
 	// Create a database connection pool, using the
 	// openDB() function. Pass openDB() the DSN from
-	// the command line flag.
+	// the config.
 	// Defer a call to db.Close(), so the connection
 	// pool is closed before the main() function exits
-	db, err := openDB(*dsn)
+	db, err := openDB(cfg.dsn)
 	if err != nil {
 		errorLog.Fatal(err)
 	}
 	defer db.Close()
 
+	// Snippet search needs SQLite's FTS5 extension, so
+	// refuse to start with a clear error if the binary
+	// was built without it
+	err = migrations.CheckFTS5(db)
+	if err != nil {
+		db.Close()
+		errorLog.Fatal(err)
+	}
+
+	// If the "migrate" subcommand was given, manage the
+	// database schema and exit without starting the
+	// server. migrate() - cmd/web/migrate.go
+	if flags.Arg(0) == "migrate" {
+		err = migrate(db, os.Stdout, flags.Args()[1:])
+		if err != nil {
+			db.Close()
+			errorLog.Fatal(err)
+		}
+		return
+	}
+
+	// Load or generate the TLS certificate now, after
+	// the subcommands, which don't need one, so a missing
+	// one is reported before anything starts.
+	// newTLSConfig() - cmd/web/certs.go
+	tlsConfig, err := newTLSConfig(cfg, errorLog)
+	if err != nil {
+		db.Close()
+		errorLog.Fatal(err)
+	}
+
+	// Bring the database schema up to date before
+	// anything uses it, unless turned off by the
+	// "auto-migrate" flag.
+	if cfg.autoMigrate {
+		applied, err := migrations.Up(db)
+		for _, m := range applied {
+			infoLog.Printf("Applied migration %04d_%s", m.Version, m.Name)
+		}
+		if err != nil {
+			errorLog.Fatal(err)
+		}
+	}
+
 	// Initialize a new template cache.
 	// newTemplateCache() - cmd/web/templates.go
 	templateCache, err := newTemplateCache()
@@ -105,12 +198,14 @@ func main() {
 	// The scs.New() function returns a pointer to a struct
 	// which holds configuration settings for the sessions.
 	// Configure to use SQLite as session store, setting
-	// a lifetime of 12 hours for session. Set "Secure"
+	// the lifetime of sessions from the config. Set "Secure"
 	// to ensure a cookie will only be sent using an
-	// HTTPS connection.
+	// HTTPS connection. realClient() takes it off again
+	// for requests made over plain HTTP.
+	sessionStore := sqlite3store.New(db)
 	sessionManager := scs.New()
-	sessionManager.Store = sqlite3store.New(db)
-	sessionManager.Lifetime = 12 * time.Hour
+	sessionManager.Store = sessionStore
+	sessionManager.Lifetime = cfg.sessionLifetime
 	sessionManager.Cookie.Secure = true
 
 	// Initialize a new instance of the application struct,
@@ -125,6 +220,9 @@ func main() {
 	//	5. templateCache - template in-memory cache
 	// 	6. formDecoder - decodes all form input
 	//	7. sessionManager - manages all user sessions
+	//	8. sessions - session model for tidying the session store
+	//	9. config - configuration settings
+	//	10. unlocks - limits wrong snippet passwords from each client
 	app := &application{
 		errorLog: 			errorLog,
 		infoLog:  			infoLog,
@@ -133,14 +231,17 @@ func main() {
 		templateCache: 	templateCache,
 		formDecoder: 		formDecoder,
 		sessionManager: sessionManager,
+		sessions:				&models.SessionModel{DB: db},
+		config:					*cfg,
+		unlocks:				newAttemptLimiter(unlockAttempts, unlockWindow),
 	}
 
-	// Initialize a tls.Config struct to hold non-default
-	// TLS settings for the server. Here, change the curve
-	// preference value, so that only elliptic curves with
-	// assembly implementations are used.
-	tlsConfig := &tls.Config{
-		CurvePreferences: []tls.CurveID{tls.X25519, tls.CurveP256},
+	// Start the background sweeper which purges expired
+	// data from the database, unless it has been turned
+	// off. Closing the done channel tells it to stop.
+	done := make(chan struct{})
+	if cfg.sweep.interval > 0 {
+		app.startSweeper(done)
 	}
 
 	// Initialize a new http.Server struct using the
@@ -153,20 +254,33 @@ func main() {
 	//	6.	ReadTimeout: Max time for reading entire request
 	//	7.	WriteTimeout: max time before timing out writes of the response
 	srv := &http.Server{
-		Addr:     *addr,
+		Addr:     cfg.addr,
 		ErrorLog: errorLog,
 		Handler:  app.routes(),
 		TLSConfig: tlsConfig,
-		IdleTimeout: time.Minute,
-		ReadTimeout: 5 * time.Second,
-		WriteTimeout: 10 * time.Second,
-	}
-
-	// Start server using defined loggers. Using the
-	// ListenAndServeTLS() to start an HTTPS server,
-	// passing in the paths to the TLS certificate and
-	// corresponding private key as the two parameters.
-	infoLog.Printf("Starting server on port %s", *addr)
-	errSrv := srv.ListenAndServeTLS("./tls/cert.pem", "./tls/key.pem")
-	errorLog.Fatal(errSrv)
+		IdleTimeout: cfg.timeouts.idle,
+		ReadTimeout: cfg.timeouts.read,
+		WriteTimeout: cfg.timeouts.write,
+	}
+
+	// Run the server until it fails or is told to stop
+	// by a signal. serve() - cmd/web/server.go
+	err = app.serve(srv, done)
+
+	// Stop the session store's own clean up goroutine,
+	// then close the database connection pool here rather
+	// than leaving it to the deferred call, because
+	// os.Exit() doesn't run deferred calls
+	sessionStore.StopCleanup()
+	if closeErr := db.Close(); closeErr != nil && err == nil {
+		err = closeErr
+	}
+
+	// Exit with status 1 if the server failed, or didn't
+	// stop cleanly, so service managers can tell. A clean
+	// shutdown exits with status 0.
+	if err != nil {
+		errorLog.Print(err)
+		os.Exit(1)
+	}
 }
@@ -2,23 +2,28 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
+	"strings"
 
+	"github.com/justinas/alice"
 	"github.com/justinas/nosurf"
+	"github.com/robwestbrook/snippetbox/internal/models"
 )
 
 /*
 	secureHeaders function adds headers to increase
-	app security.
+	app security. The Content-Security-Policy comes
+	from the config, and isn't sent if it is empty.
 */
-func secureHeaders(next http.Handler) http.Handler {
+func (app *application) secureHeaders(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 
 		// Set headers
-		w.Header().Set(
-			"Content-Security-Policy",
-			"default-src 'self'; style-src 'self' fonts.googleapis.com; font-src fonts-gstatic.com")
+		if app.config.csp != "" {
+			w.Header().Set("Content-Security-Policy", app.config.csp)
+		}
 		
 		w.Header().Set("Referrer-Policy", "origin-when-cross-origin")
 		w.Header().Set("X-Content-Type-Options", "nosniff")
@@ -99,7 +104,9 @@ func (app *application) requireAuthentication(next http.Handler) http.Handler {
 // noSurf function creates a middleware function using
 // the NoSurf package. This creates a customized CSRF
 // cookie with the secure, path, and http only 
-// attributes set.
+// attributes set. As with the session cookie,
+// realClient() takes the secure attribute off again
+// for requests made over plain HTTP.
 // This prevents CSRF attacks.
 func noSurf(next http.Handler) http.Handler {
 	csrfHandler := nosurf.New(next)
@@ -112,6 +119,15 @@ func noSurf(next http.Handler) http.Handler {
 	return csrfHandler
 }
 
+// withAuthenticatedUser function returns a copy of the
+// request whose context records that the user with the
+// ID id is authenticated.
+func withAuthenticatedUser(r *http.Request, id int) *http.Request {
+	ctx := context.WithValue(r.Context(), isAuthenticatedContextKey, true)
+	ctx = context.WithValue(ctx, authenticatedUserIDContextKey, id)
+	return r.WithContext(ctx)
+}
+
 // Authenticate function retrieves the user's ID from
 // the database. Checks the database to see if the ID
 // corresponds to a valid user. Updates the request
@@ -144,17 +160,96 @@ func (app *application) authenticate(next http.Handler) http.Handler {
 
 		// If a matching user is found, creatre a new copy
 		// of the request, with an isAuthenticatedContextKey
-		// value of true and assign it to r.
+		// value of true and the user's ID, and assign it
+		// to r.
 		if exists {
-			ctx := context.WithValue(
-				r.Context(), 
-				isAuthenticatedContextKey,
-				true,
-			)
-			r = r.WithContext(ctx)
+			r = withAuthenticatedUser(r, id)
 		}
 
 		// Call the next handler in the middleware chain
 		next.ServeHTTP(w, r)
 	})
-}
\ No newline at end of file
+}
+
+/*
+	apiAuthenticate function authenticates requests to
+	the API with a personal API token, sent in an
+	"Authorization: Bearer <token>" header. The request
+	context is filled in the same way as authenticate
+	does for a session, along with the token so its
+	scope can be checked. Requests without an
+	Authorization header carry on unauthenticated, but a
+	request with a bad token is refused.
+*/
+func (app *application) apiAuthenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The response depends on who is asking, so caches
+		// must not share it between users
+		w.Header().Add("Vary", "Authorization")
+
+		header := r.Header.Get("Authorization")
+		if header == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		scheme, token, ok := strings.Cut(header, " ")
+		if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+			app.apiAuthenticationRequired(w, "the Authorization header must be in the format \"Bearer TOKEN\"")
+			return
+		}
+
+		t, err := app.users.AuthenticateToken(token)
+		if err != nil {
+			if errors.Is(err, models.ErrInvalidCredentials) {
+				app.apiAuthenticationRequired(w, "invalid or revoked API token")
+			} else {
+				app.apiServerError(w, err)
+			}
+			return
+		}
+
+		// Call the next handler in the chain, as the
+		// token's user
+		r = withAuthenticatedUser(r, t.UserID)
+		r = r.WithContext(context.WithValue(r.Context(), apiTokenContextKey, t))
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+/*
+	apiRequireAuthentication function refuses API
+	requests which aren't authenticated, like
+	requireAuthentication does for pages.
+*/
+func (app *application) apiRequireAuthentication(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !app.isAuthenticated(r) {
+			app.apiAuthenticationRequired(w, "you must be authenticated to access this resource")
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+/*
+	apiRequireScope function returns middleware which
+	refuses API requests made with a token that doesn't
+	have the given scope. It must come after
+	apiRequireAuthentication.
+*/
+func (app *application) apiRequireScope(scope string) alice.Constructor {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			t, ok := r.Context().Value(apiTokenContextKey).(*models.Token)
+			if !ok || !t.Allows(scope) {
+				app.apiErrorResponse(w, http.StatusForbidden, fmt.Sprintf("your API token needs the %s scope", scope), nil)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/robwestbrook/snippetbox/internal/migrations"
+)
+
+/*
+	migrate function runs the "migrate" subcommand, which
+	manages the database schema from the command line.
+	Flags go before the subcommand, for example
+	"web -dsn ./snippetbox.db migrate status".
+
+	Actions available:
+	1. up - apply every pending migration
+	2. down [n] - reverse the last n migrations (default: 1)
+	3. status - list every migration and when it was applied
+*/
+func migrate(db *sql.DB, out io.Writer, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: migrate up|down [n]|status")
+	}
+
+	switch args[0] {
+	case "up":
+		applied, err := migrations.Up(db)
+		for _, m := range applied {
+			fmt.Fprintf(out, "Applied %04d_%s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			return err
+		}
+		if len(applied) == 0 {
+			fmt.Fprintln(out, "No pending migrations")
+		}
+
+	case "down":
+		// Reverse one migration unless told otherwise
+		steps := 1
+		if len(args) > 1 {
+			n, err := strconv.Atoi(args[1])
+			if err != nil || n < 1 {
+				return fmt.Errorf("migrate down: %q is not a positive number of steps", args[1])
+			}
+			steps = n
+		}
+
+		reversed, err := migrations.Down(db, steps)
+		for _, m := range reversed {
+			fmt.Fprintf(out, "Reversed %04d_%s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			return err
+		}
+		if len(reversed) == 0 {
+			fmt.Fprintln(out, "No applied migrations")
+		}
+
+	case "status":
+		statuses, err := migrations.Statuses(db)
+		if err != nil {
+			return err
+		}
+		for _, s := range statuses {
+			applied := "pending"
+			if !s.Applied.IsZero() {
+				applied = "applied " + humanDate(s.Applied)
+			}
+			fmt.Fprintf(out, "%04d_%-40s %s\n", s.Version, s.Name, applied)
+		}
+
+	default:
+		return fmt.Errorf("migrate: unknown action %q, want up, down or status", args[0])
+	}
+
+	return nil
+}
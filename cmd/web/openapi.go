@@ -0,0 +1,447 @@
+package main
+
+import (
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/robwestbrook/snippetbox/internal/models"
+	"github.com/robwestbrook/snippetbox/internal/validator"
+)
+
+// object is shorthand for the JSON objects the OpenAPI
+// document is built from.
+type object = map[string]any
+
+// apiOperations describes each route of the JSON API
+// for the OpenAPI document, keyed by the route's method
+// and path as they are given in apiRoutes(). Security
+// and the authentication error responses are added from
+// the route's scope.
+var apiOperations = map[string]object{
+	"GET /api/openapi.json": {
+		"summary": "Get this OpenAPI document",
+		"responses": object{
+			"200": object{
+				"description": "The OpenAPI document",
+				"content":     object{"application/json": object{"schema": object{"type": "object"}}},
+			},
+		},
+	},
+	"GET /api/v1/snippets": {
+		"summary": "List snippets a page at a time",
+		"parameters": []object{
+			queryParameter("sort", "The order to list snippets in", object{"type": "string", "enum": sortKeys(), "default": models.DefaultSort}),
+			queryParameter("tag", "Only list snippets with this tag", object{"type": "string", "pattern": validator.TagRX.String()}),
+			queryParameter("limit", "The number of snippets on the page", object{"type": "integer", "minimum": 1, "maximum": 100}),
+			queryParameter("after", "The next cursor of the page before this one", object{"type": "string"}),
+			queryParameter("before", "The prev cursor of the page after this one", object{"type": "string"}),
+		},
+		"responses": object{
+			"200": jsonResponse("A page of snippets", object{
+				"type": "object",
+				"properties": object{
+					"snippets": object{"type": "array", "items": schemaRef("Snippet")},
+					"page":     schemaRef("Page"),
+				},
+			}),
+			"400": responseRef("BadRequest"),
+			"422": responseRef("ValidationFailed"),
+		},
+	},
+	"POST /api/v1/snippets": {
+		"summary":     "Create a snippet",
+		"requestBody": jsonRequest("SnippetCreate"),
+		"responses": object{
+			"201": jsonResponse("The new snippet", envelopeSchema("snippet", schemaRef("Snippet"))),
+			"400": responseRef("BadRequest"),
+			"422": responseRef("ValidationFailed"),
+		},
+	},
+	"GET /api/v1/snippets/:slug": {
+		"summary":     "Get a snippet",
+		"description": "Snippets that burn after reading are sent with empty content, unless they are sent to their owner. Reveal them to read them.",
+		"parameters":  []object{slugParameter(), passwordParameter()},
+		"responses": object{
+			"200": jsonResponse("The snippet", envelopeSchema("snippet", schemaRef("Snippet"))),
+			"403": errorResponse("The snippet has a password, and it wasn't sent or is wrong"),
+			"404": responseRef("NotFound"),
+			"429": errorResponse("Too many wrong passwords were sent, try again after Retry-After seconds"),
+		},
+	},
+	"POST /api/v1/snippets/:slug/reveal": {
+		"summary":     "Read a snippet, burning it if it burns after reading",
+		"description": "Snippets that burn after reading are deleted as they are sent, unless they are sent to their owner, so they can only be revealed once. Other snippets are sent as by GET.",
+		"parameters":  []object{slugParameter(), passwordParameter()},
+		"responses": object{
+			"200": jsonResponse("The snippet, with its content", envelopeSchema("snippet", schemaRef("Snippet"))),
+			"403": errorResponse("The snippet has a password, and it wasn't sent or is wrong"),
+			"404": responseRef("NotFound"),
+			"429": errorResponse("Too many wrong passwords were sent, try again after Retry-After seconds"),
+		},
+	},
+	"PUT /api/v1/snippets/:slug": {
+		"summary":     "Update one of your snippets",
+		"parameters":  []object{slugParameter()},
+		"requestBody": jsonRequest("SnippetUpdate"),
+		"responses": object{
+			"200": jsonResponse("The updated snippet", envelopeSchema("snippet", schemaRef("Snippet"))),
+			"400": responseRef("BadRequest"),
+			"404": responseRef("NotFound"),
+			"422": responseRef("ValidationFailed"),
+		},
+	},
+	"DELETE /api/v1/snippets/:slug": {
+		"summary":    "Move one of your snippets to the trash",
+		"parameters": []object{slugParameter()},
+		"responses": object{
+			"200": jsonResponse("The snippet was moved to the trash", envelopeSchema("message", object{"type": "string"})),
+			"404": responseRef("NotFound"),
+		},
+	},
+	"POST /api/v1/paste": {
+		"summary":     "Create a snippet from the raw request body",
+		"parameters":  pasteParameters(),
+		"requestBody": object{
+			"required": true,
+			"content":  object{"text/plain": object{"schema": object{"type": "string", "minLength": 1}}},
+		},
+		"responses": object{
+			"201": object{
+				"description": "The URL of the new snippet",
+				"headers":     object{"Location": object{"schema": object{"type": "string", "format": "uri"}}},
+				"content":     object{"text/plain": object{"schema": object{"type": "string", "format": "uri"}}},
+			},
+			"400": responseRef("BadRequest"),
+			"413": errorResponse("The body is larger than the paste limit"),
+			"422": responseRef("ValidationFailed"),
+		},
+	},
+	"GET /api/v1/user": {
+		"summary": "Get the user the API token belongs to",
+		"responses": object{
+			"200": jsonResponse("The user", envelopeSchema("user", schemaRef("User"))),
+		},
+	},
+}
+
+/*
+	openAPIDocument function builds the OpenAPI 3
+	document describing every route in apiRoutes().
+*/
+func (app *application) openAPIDocument() object {
+	paths := object{}
+
+	for _, route := range app.apiRoutes() {
+		op, ok := apiOperations[route.method+" "+route.path]
+		if !ok {
+			continue
+		}
+
+		// Copy the operation, so adding to it doesn't
+		// change apiOperations
+		operation := object{}
+		for k, v := range op {
+			operation[k] = v
+		}
+
+		// Routes with a scope need an API token
+		if route.scope != "" {
+			responses := object{
+				"401": responseRef("Unauthorized"),
+				"403": responseRef("Forbidden"),
+			}
+			for k, v := range op["responses"].(object) {
+				responses[k] = v
+			}
+			operation["responses"] = responses
+			operation["security"] = []object{{"bearerAuth": []string{}}}
+			operation["description"] = "Needs an API token with the " + route.scope + " scope."
+		}
+
+		path := openAPIPath(route.path)
+		if paths[path] == nil {
+			paths[path] = object{}
+		}
+		paths[path].(object)[strings.ToLower(route.method)] = operation
+	}
+
+	return object{
+		"openapi": "3.0.3",
+		"info": object{
+			"title":       "Snippetbox API",
+			"version":     "1.0.0",
+			"description": "Create and fetch snippets. Errors are always sent as {\"error\": {...}}.",
+		},
+		"paths": paths,
+		"components": object{
+			"securitySchemes": object{
+				"bearerAuth": object{
+					"type":        "http",
+					"scheme":      "bearer",
+					"description": "A personal API token, created on the settings page",
+				},
+			},
+			"schemas": object{
+				"Snippet":       schemaOf(reflect.TypeOf(models.Snippet{})),
+				"User":          schemaOf(reflect.TypeOf(models.User{})),
+				"Page":          schemaOf(reflect.TypeOf(models.Page{})),
+				"Error":         envelopeSchema("error", schemaOf(reflect.TypeOf(apiError{}))),
+				"SnippetCreate": snippetInputSchema(reflect.TypeOf(snippetCreateInput{})),
+				"SnippetUpdate": snippetInputSchema(reflect.TypeOf(snippetUpdateInput{})),
+			},
+			"responses": object{
+				"BadRequest":       errorResponse("The request body or query string is malformed"),
+				"Unauthorized":     errorResponse("The API token is missing or invalid"),
+				"Forbidden":        errorResponse("The API token doesn't allow this, or the snippet isn't yours"),
+				"NotFound":         errorResponse("The snippet doesn't exist"),
+				"ValidationFailed": errorResponse("The request failed validation. The error's fields say why."),
+			},
+		},
+	}
+}
+
+/*
+	openAPI function serves the OpenAPI document.
+*/
+func (app *application) openAPI(w http.ResponseWriter, r *http.Request) {
+	app.writeJSON(w, http.StatusOK, envelope(app.openAPIDocument()))
+}
+
+/*
+	snippetInputSchema function returns the schema of a
+	request body creating or updating a snippet, with the
+	same rules as snippetCreateForm checks.
+*/
+func snippetInputSchema(t reflect.Type) object {
+	schema := schemaOf(t)
+	schema["required"] = []string{"title", "content"}
+
+	properties := schema["properties"].(object)
+	properties["title"] = object{"type": "string", "minLength": 1, "maxLength": maxTitleChars}
+	properties["content"] = object{"type": "string", "minLength": 1}
+	properties["language"] = object{
+		"type":        "string",
+		"enum":        append(languageKeys(), autoDetect),
+		"default":     autoDetect,
+		"description": "The language to highlight the snippet as, or auto to detect it",
+	}
+	properties["visibility"] = object{
+		"type":        "string",
+		"enum":        models.Visibilities,
+		"description": "Who can see the snippet, public if it isn't given for a new snippet and unchanged if it isn't given for an update. Unlisted snippets are only reachable by their slug.",
+	}
+	properties["tags"] = object{
+		"type":        "array",
+		"maxItems":    maxTags,
+		"items":       object{"type": "string", "pattern": validator.TagRX.String()},
+		"description": "Tags are lowercased, and repeated tags are dropped",
+	}
+	if _, ok := properties["burn_after_reading"]; ok {
+		properties["burn_after_reading"] = object{
+			"type":        "boolean",
+			"default":     false,
+			"description": "Delete the snippet the first time someone else reads it. Public snippets that burn after reading are made unlisted.",
+		}
+	}
+	if _, ok := properties["encrypted"]; ok {
+		properties["encrypted"] = object{
+			"type":        "boolean",
+			"default":     false,
+			"description": "The content was encrypted by the client, and is the base64 encoding of a 12 byte AES-GCM nonce followed by the ciphertext. The key is never sent to the server. Encrypted snippets are plain text and aren't searchable.",
+		}
+	}
+	if _, ok := properties["password"]; ok {
+		properties["password"] = object{
+			"type":        "string",
+			"minLength":   8,
+			"description": "Only show the snippet to people who know this password. Snippets with a password aren't listed or searchable.",
+		}
+	}
+	properties["expires"] = object{
+		"oneOf": []object{
+			{"type": "string", "enum": expiryKeys()},
+			{"type": "integer", "minimum": 1, "maximum": maxExpiryDays},
+		},
+		"description": "How long until the snippet expires, as one of the options or a number of days. New snippets get the server's default expiry if it isn't given, and updates keep the current expiry date. Servers can set a maximum, which rules out some options. Snippets that never expire have an expires date of 9999-12-31.",
+	}
+	properties["expires_at"] = object{
+		"type":        "string",
+		"format":      "date-time",
+		"description": "The exact time the snippet expires, used instead of expires",
+	}
+
+	return schema
+}
+
+/*
+	schemaOf function returns a JSON schema for values
+	of type t when they are encoded as JSON, using the
+	json struct tags to name the properties of structs.
+*/
+func schemaOf(t reflect.Type) object {
+	if t == reflect.TypeOf(time.Time{}) {
+		return object{"type": "string", "format": "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return schemaOf(t.Elem())
+	case reflect.String:
+		return object{"type": "string"}
+	case reflect.Bool:
+		return object{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return object{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return object{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return object{"type": "array", "items": schemaOf(t.Elem())}
+	case reflect.Map:
+		return object{"type": "object", "additionalProperties": schemaOf(t.Elem())}
+	case reflect.Struct:
+		properties := object{}
+
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+
+			name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+			if name == "-" {
+				continue
+			}
+			if name == "" {
+				name = field.Name
+			}
+
+			properties[name] = schemaOf(field.Type)
+		}
+
+		return object{"type": "object", "properties": properties}
+	default:
+		return object{}
+	}
+}
+
+/*
+	openAPIPath function turns a router path like
+	"/snippets/:slug" into an OpenAPI path like
+	"/snippets/{slug}".
+*/
+func openAPIPath(path string) string {
+	parts := strings.Split(path, "/")
+	for i, part := range parts {
+		if strings.HasPrefix(part, ":") {
+			parts[i] = "{" + part[1:] + "}"
+		}
+	}
+	return strings.Join(parts, "/")
+}
+
+/*
+	sortKeys function returns the names of the orders
+	snippets can be listed in, in alphabetical order.
+*/
+func sortKeys() []string {
+	keys := make([]string, 0, len(models.SortOrders))
+	for k := range models.SortOrders {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// The helpers below build the parts of the document
+// which are used more than once.
+
+func schemaRef(name string) object {
+	return object{"$ref": "#/components/schemas/" + name}
+}
+
+func responseRef(name string) object {
+	return object{"$ref": "#/components/responses/" + name}
+}
+
+func envelopeSchema(key string, schema object) object {
+	return object{
+		"type":       "object",
+		"properties": object{key: schema},
+		"required":   []string{key},
+	}
+}
+
+func jsonResponse(description string, schema object) object {
+	return object{
+		"description": description,
+		"content":     object{"application/json": object{"schema": schema}},
+	}
+}
+
+func jsonRequest(schema string) object {
+	return object{
+		"required": true,
+		"content":  object{"application/json": object{"schema": schemaRef(schema)}},
+	}
+}
+
+func errorResponse(description string) object {
+	return jsonResponse(description, schemaRef("Error"))
+}
+
+func queryParameter(name string, description string, schema object) object {
+	return object{"name": name, "in": "query", "description": description, "schema": schema}
+}
+
+// pasteParameters returns the options of a raw paste,
+// which can each be in the query string or in an
+// X-Snippet- header. The rules are the ones for the
+// SnippetCreate schema.
+func pasteParameters() []object {
+	create := snippetInputSchema(reflect.TypeOf(snippetCreateInput{}))["properties"].(object)
+
+	title := object{"type": "string", "maxLength": maxTitleChars}
+	tags := object{"type": "string", "description": "Tags separated by commas"}
+
+	var parameters []object
+	for _, p := range []struct {
+		name		string
+		schema	any
+	}{
+		{"title", title},
+		{"language", create["language"]},
+		{"visibility", create["visibility"]},
+		{"tags", tags},
+		{"expires", create["expires"]},
+	} {
+		header := "X-Snippet-" + strings.ToUpper(p.name[:1]) + p.name[1:]
+		parameters = append(parameters,
+			object{"name": p.name, "in": "query", "schema": p.schema, "description": "Defaults to the " + header + " header"},
+			object{"name": header, "in": "header", "schema": p.schema},
+		)
+	}
+	return parameters
+}
+
+func slugParameter() object {
+	return object{
+		"name":        "slug",
+		"in":          "path",
+		"required":    true,
+		"description": "The slug of the snippet",
+		"schema":      object{"type": "string"},
+	}
+}
+
+func passwordParameter() object {
+	return object{
+		"name":        "X-Snippet-Password",
+		"in":          "header",
+		"description": "The password of a snippet with one, unless you own it",
+		"schema":      object{"type": "string"},
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/robwestbrook/snippetbox/internal/models"
+)
+
+func TestOpenAPIRoutes(t *testing.T) {
+	// The routes aren't called, so the application
+	// doesn't need any dependencies.
+	app := &application{}
+
+	doc := app.openAPIDocument()
+	paths := doc["paths"].(object)
+
+	// Every API route must be in the document, with
+	// security if it needs an API token.
+	routes := map[string]bool{}
+
+	for _, route := range app.apiRoutes() {
+		key := route.method + " " + route.path
+		routes[key] = true
+
+		path, _ := paths[openAPIPath(route.path)].(object)
+		operation, ok := path[strings.ToLower(route.method)].(object)
+		if !ok {
+			t.Errorf("%s is missing from the OpenAPI document", key)
+			continue
+		}
+
+		_, secured := operation["security"]
+		if secured != (route.scope != "") {
+			t.Errorf("%s: got security %t, want %t", key, secured, route.scope != "")
+		}
+	}
+
+	// Every operation described must be a real route
+	for key := range apiOperations {
+		if !routes[key] {
+			t.Errorf("%s is described but isn't in apiRoutes()", key)
+		}
+	}
+
+	// The document must encode as JSON
+	_, err := json.Marshal(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSchemaOf(t *testing.T) {
+	// Properties are named by their json tags, and
+	// fields tagged "-" are left out.
+	properties := schemaOf(reflect.TypeOf(models.Snippet{}))["properties"].(object)
+
+	for _, name := range []string{"id", "title", "content", "created", "expires", "author", "tags", "language"} {
+		if _, ok := properties[name]; !ok {
+			t.Errorf("missing property %q", name)
+		}
+	}
+	for _, name := range []string{"UserID", "Deleted"} {
+		if _, ok := properties[name]; ok {
+			t.Errorf("got property %q, want it left out", name)
+		}
+	}
+}
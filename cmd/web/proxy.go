@@ -0,0 +1,257 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// ipPrefixes defines a type to hold the trusted proxy
+// networks. It is a flag.Value, set from a comma
+// separated list of CIDRs, like "10.0.0.0/8,::1". A
+// bare IP address is taken as a network of just that
+// address.
+type ipPrefixes []netip.Prefix
+
+/*
+	String function returns the networks as the comma
+	separated list they are set from.
+*/
+func (p *ipPrefixes) String() string {
+	if p == nil {
+		return ""
+	}
+
+	s := make([]string, len(*p))
+	for i, prefix := range *p {
+		s[i] = prefix.String()
+	}
+	return strings.Join(s, ",")
+}
+
+/*
+	Set function replaces the networks with those in the
+	comma separated list in value.
+*/
+func (p *ipPrefixes) Set(value string) error {
+	var prefixes ipPrefixes
+
+	for _, field := range strings.Split(value, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+
+		if !strings.Contains(field, "/") {
+			addr, err := netip.ParseAddr(field)
+			if err != nil {
+				return err
+			}
+			prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+
+		prefix, err := netip.ParsePrefix(field)
+		if err != nil {
+			return err
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+
+	*p = prefixes
+	return nil
+}
+
+/*
+	contains function returns true if addr is in one of
+	the networks.
+*/
+func (p ipPrefixes) contains(addr netip.Addr) bool {
+	// IPv4 addresses can arrive mapped into IPv6, as
+	// ::ffff:10.0.0.1, which the IPv4 networks wouldn't
+	// match
+	addr = addr.Unmap()
+
+	for _, prefix := range p {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+	realClient function works out who made the request
+	and whether they used HTTPS, for requests coming
+	through a reverse proxy. When the request comes from
+	a trusted proxy, the client's address is taken from
+	the X-Forwarded-For header and the scheme from the
+	X-Forwarded-Proto header. Otherwise the headers are
+	ignored, as anyone could have sent them. The address
+	replaces r.RemoteAddr, with a port of 0 as the real
+	port isn't known, so r.RemoteAddr keeps the host:port
+	form every handler expects. The scheme is kept in the
+	request context for isSecure(). Cookies sent on
+	requests which didn't use HTTPS aren't marked
+	Secure, as browsers would drop them.
+*/
+func (app *application) realClient(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		secure := r.TLS != nil
+
+		remote, err := parseRemoteAddr(r.RemoteAddr)
+		if err == nil && app.config.trustedProxies.contains(remote) {
+			if client, ok := forwardedFor(r.Header.Values("X-Forwarded-For"), app.config.trustedProxies); ok {
+				r.RemoteAddr = net.JoinHostPort(client.String(), "0")
+			}
+
+			switch proto := forwardedProto(r.Header.Get("X-Forwarded-Proto")); proto {
+			case "https":
+				secure = true
+			case "http":
+				secure = false
+			}
+		}
+
+		r = r.WithContext(context.WithValue(r.Context(), isSecureContextKey, secure))
+
+		if secure {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		cw := &insecureCookieWriter{ResponseWriter: w}
+		next.ServeHTTP(cw, r)
+
+		// The handler may not have written anything, in
+		// which case the server writes the headers after
+		// it returns
+		cw.clearSecure()
+	})
+}
+
+/*
+	isSecure function returns true if the client made
+	the request using HTTPS, either directly or to a
+	trusted proxy.
+*/
+func isSecure(r *http.Request) bool {
+	secure, ok := r.Context().Value(isSecureContextKey).(bool)
+	if !ok {
+		return r.TLS != nil
+	}
+	return secure
+}
+
+/*
+	parseRemoteAddr function returns the IP address in
+	a request's RemoteAddr, which is normally an address
+	and port.
+*/
+func parseRemoteAddr(remoteAddr string) (netip.Addr, error) {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+	return netip.ParseAddr(host)
+}
+
+/*
+	forwardedFor function returns the client's address
+	from the X-Forwarded-For header values. Each proxy
+	adds the address it received the request from to the
+	end of the list, so the list is read from the end,
+	skipping trusted proxies. The first address which
+	isn't a trusted proxy is the client, as anything
+	before it could have been made up by the client.
+*/
+func forwardedFor(values []string, trusted ipPrefixes) (netip.Addr, bool) {
+	var addrs []string
+	for _, value := range values {
+		addrs = append(addrs, strings.Split(value, ",")...)
+	}
+
+	var client netip.Addr
+	for i := len(addrs) - 1; i >= 0; i-- {
+		addr, err := netip.ParseAddr(strings.TrimSpace(addrs[i]))
+		if err != nil {
+			break
+		}
+
+		client = addr.Unmap()
+		if !trusted.contains(client) {
+			break
+		}
+	}
+
+	return client, client.IsValid()
+}
+
+/*
+	forwardedProto function returns the scheme in an
+	X-Forwarded-Proto header, in lowercase. When
+	proxies have added several, the first one is the
+	scheme the client used.
+*/
+func forwardedProto(value string) string {
+	proto, _, _ := strings.Cut(value, ",")
+	return strings.ToLower(strings.TrimSpace(proto))
+}
+
+// insecureCookieWriter defines a type which wraps a
+// response writer to take the Secure attribute off
+// the cookies in the response, just before the headers
+// are written. The session manager and the CSRF
+// protection always set Secure cookies, which browsers
+// won't store from a plain HTTP response.
+type insecureCookieWriter struct {
+	http.ResponseWriter
+	wroteHeader	bool
+}
+
+func (cw *insecureCookieWriter) WriteHeader(code int) {
+	cw.clearSecure()
+	cw.ResponseWriter.WriteHeader(code)
+}
+
+func (cw *insecureCookieWriter) Write(b []byte) (int, error) {
+	cw.clearSecure()
+	return cw.ResponseWriter.Write(b)
+}
+
+/*
+	Unwrap function returns the wrapped response writer,
+	for http.ResponseController.
+*/
+func (cw *insecureCookieWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
+
+/*
+	clearSecure function rewrites the Set-Cookie headers
+	without the Secure attribute, the first time it is
+	called.
+*/
+func (cw *insecureCookieWriter) clearSecure() {
+	if cw.wroteHeader {
+		return
+	}
+	cw.wroteHeader = true
+
+	header := cw.Header()
+	if len(header["Set-Cookie"]) == 0 {
+		return
+	}
+
+	// Let net/http parse the cookies, as it does for a
+	// client reading a response
+	cookies := (&http.Response{Header: http.Header{"Set-Cookie": header["Set-Cookie"]}}).Cookies()
+
+	header.Del("Set-Cookie")
+	for _, c := range cookies {
+		c.Secure = false
+		header.Add("Set-Cookie", c.String())
+	}
+}
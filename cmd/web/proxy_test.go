@@ -0,0 +1,70 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRealClient(t *testing.T) {
+	app := &application{}
+	err := app.config.trustedProxies.Set("10.0.0.0/8, ::1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name				string
+		remoteAddr	string
+		forwardedFor	[]string
+		proto				string
+		wantAddr		string
+		wantSecure	bool
+	}{
+		{"Direct", "203.0.113.7:5000", nil, "", "203.0.113.7:5000", false},
+		{"Untrusted headers", "203.0.113.7:5000", []string{"198.51.100.1"}, "https", "203.0.113.7:5000", false},
+		{"Trusted proxy", "10.0.0.2:5000", []string{"198.51.100.1"}, "https", "198.51.100.1:0", true},
+		{"Proxy chain", "10.0.0.2:5000", []string{"192.0.2.9, 198.51.100.1", "10.0.0.3"}, "HTTPS", "198.51.100.1:0", true},
+		{"IPv6 client", "10.0.0.2:5000", []string{"2001:db8::1"}, "https", "[2001:db8::1]:0", true},
+		{"Only proxies", "[::1]:5000", []string{"10.0.0.3"}, "http", "10.0.0.3:0", false},
+		{"Bad header", "10.0.0.2:5000", []string{"nonsense"}, "", "10.0.0.2:5000", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotAddr string
+			var gotSecure bool
+			handler := app.realClient(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotAddr = r.RemoteAddr
+				gotSecure = isSecure(r)
+				http.SetCookie(w, &http.Cookie{Name: "session", Value: "x", Secure: true})
+			}))
+
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.RemoteAddr = tt.remoteAddr
+			for _, value := range tt.forwardedFor {
+				r.Header.Add("X-Forwarded-For", value)
+			}
+			if tt.proto != "" {
+				r.Header.Set("X-Forwarded-Proto", tt.proto)
+			}
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, r)
+
+			if gotAddr != tt.wantAddr {
+				t.Errorf("got address %q, want %q", gotAddr, tt.wantAddr)
+			}
+			if gotSecure != tt.wantSecure {
+				t.Errorf("got secure %t, want %t", gotSecure, tt.wantSecure)
+			}
+
+			// Cookies are only Secure over HTTPS
+			cookie := rr.Header().Get("Set-Cookie")
+			if secure := strings.Contains(cookie, "Secure"); secure != tt.wantSecure {
+				t.Errorf("got cookie %q, want secure %t", cookie, tt.wantSecure)
+			}
+		})
+	}
+}
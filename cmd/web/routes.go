@@ -2,9 +2,11 @@ package main
 
 import (
 	"net/http"
+	"strings"
 
 	"github.com/julienschmidt/httprouter"
 	"github.com/justinas/alice"
+	"github.com/robwestbrook/snippetbox/internal/models"
 )
 
 /*
@@ -16,10 +18,53 @@ import (
 	-----------------------------------------------------
 	GET		|	/									| home							| home page
 
-	GET		|	/snippet/view/:id	| snippetView				| display
+	GET		|	/snippet/view/:slug	| snippetView				| display
 				|										|										| specific
 				|										|										| snippet
 
+	POST	|	/snippet/unlock/:slug	| snippetUnlockPost	| enter the
+				|											|										| password of
+				|											|										| a snippet
+
+	POST	|	/snippet/reveal/:slug	| snippetRevealPost	| show and
+				|											|										| delete a burn
+				|											|										| after reading
+				|											|										| snippet
+
+	GET		|	/snippet/raw/:slug	| snippetRaw				| snippet
+				|										|										| content as
+				|										|										| plain text
+
+	GET		|	/snippet/download/:slug	| snippetDownload	| snippet
+				|											|									| content as
+				|											|									| a file
+
+	GET		|	/snippet/view/:slug/history	| snippetHistory	| display
+				|														|									| snippet
+				|														|									| revisions
+
+	GET		|	/snippet/view/:slug/history/:version	| snippetRevision	| display
+				|																		|									| one
+				|																		|									| revision
+
+	GET		|	/snippet/view/:slug/diff	| snippetDiff			| display
+				|													|									| differences
+				|													|									| between
+				|													|									| revisions
+
+	GET		|	/snippets					| snippetArchive		| list every
+				|										|										| snippet
+
+	GET		|	/tag/:name				| snippetTag				| list snippets
+				|										|										| with a tag
+
+	GET		|	/tags							| tagCloud					| display
+				|										|										| every tag
+
+	GET		|	/search						| search						| display
+				|										|										| search
+				|										|										| results
+
 	GET		|	/snippet/create		| snippetCreate			| Display form
 				|										|										| to create
 				|										|										| new snippet
@@ -28,6 +73,29 @@ import (
 				|										|										| new
 				|										|										| snippet
 
+	GET		|	/snippet/mine			|	snippetMine				| Display the
+				|										|										| logged in
+				|										|										| user's snippets
+
+	GET		|	/snippet/edit/:slug	|	snippetEdit				| Display form
+				|										|										| to edit a
+				|										|										| snippet
+
+	POST	|	/snippet/edit/:slug	|	snippetEditPost		| Update a
+				|										|										| snippet
+
+	POST	|	/snippet/delete/:slug	|	snippetDeletePost	| Move a
+				|											|										| snippet to
+				|											|										| the trash
+
+	GET		|	/snippet/trash		|	snippetTrash			| Display the
+				|										|										| logged in
+				|										|										| user's trash
+
+	POST	|	/snippet/restore/:slug	|	snippetRestorePost	| Restore a
+				|											|											| snippet from
+				|											|											| the trash
+
 	GET		| /user/signup			| userSignup				| Display form
 				|										|										| for signing up
 				|										|										| new user
@@ -43,9 +111,59 @@ import (
 				|										|										| and login
 				|										|										| a user
 
+	GET		| /user/settings		| userSettings			| Display the
+				|										|										| user's API
+				|										|										| tokens
+
+	POST	| /user/tokens			| userTokenCreatePost	| Create an
+				|										|											| API token
+
+	POST	| /user/tokens/revoke/:id	| userTokenRevokePost	| Revoke an
+				|													|											| API token
+
 	POST	| /user/logout			| userLogoutPost		| Logout a
 				|										|										| user
 
+	GET		| /api/v1/snippets	| apiSnippetList		| list snippets
+				|										|										| as JSON
+
+	POST	| /api/v1/snippets	| apiSnippetCreate	| create a
+				|										|										| snippet from
+				|										|										| JSON
+
+	GET		| /api/v1/snippets/:slug	| apiSnippetGet	| get a snippet
+				|												|								| as JSON
+
+	POST	| /api/v1/snippets/:slug/reveal	| apiSnippetReveal	| read a
+				|															|										| snippet as
+				|															|										| JSON, burning
+				|															|										| it if needed
+
+	PUT		| /api/v1/snippets/:slug	| apiSnippetUpdate	| update a
+				|												|										| snippet from
+				|												|										| JSON
+
+	DELETE	| /api/v1/snippets/:slug	| apiSnippetDelete	| move a
+					|												|										| snippet to
+					|												|										| the trash
+
+	POST	| /api/v1/paste		| apiPaste					| create a
+				|										|										| snippet from
+				|										|										| a raw body
+
+	GET		| /api/v1/user			| apiUser						| get the
+				|										|										| token's user
+				|										|										| as JSON
+
+	GET		| /api/openapi.json	| openAPI				| the OpenAPI
+				|										|								| document for
+				|										|								| the API
+
+	GET		| /highlight.css		|	highlightCSS			| serve the
+				|										|										| styles for
+				|										|										| highlighted
+				|										|										| code
+
 	GET		| /static/*filepath	|	http.Fileserver		| serve
 				|										|										|	static
 				|										|										| file
@@ -56,11 +174,26 @@ func (app *application) routes() http.Handler {
 
 	// Create a handler function wrapping the notFound()
 	// helper function. Assign it as the custom handler
-	// for 404 not found responses.
+	// for 404 not found responses. API clients get
+	// their errors as JSON.
 	router.NotFound = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/api/") {
+			app.apiNotFound(w)
+			return
+		}
 		app.notFound(w)
 	})
 
+	// Do the same for 405 method not allowed responses.
+	// The router sets the Allow header before calling it.
+	router.MethodNotAllowed = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/api/") {
+			app.apiMethodNotAllowed(w, r)
+			return
+		}
+		app.clientError(w, http.StatusMethodNotAllowed)
+	})
+
 	// Create a static file server and use the mux.Handle()
 	// function to register the file server as the
 	// handler for all URL paths that start with "/static/"
@@ -73,6 +206,11 @@ func (app *application) routes() http.Handler {
 		fileServer),
 	)
 
+	// The styles for highlighted code are generated from
+	// the chroma style, so they always match the classes
+	// in the highlighted HTML
+	router.HandlerFunc(http.MethodGet, "/highlight.css", app.highlightCSS)
+
 	// Create a new middleware chain containing middleware
 	// specific to dynamic application routes. Alice
 	// manages middleware chains.
@@ -91,7 +229,18 @@ func (app *application) routes() http.Handler {
 	// handlers. Wrap the unprotextedhandlers with the 
 	// DYNAMIC middleware for session control.
 	router.Handler(http.MethodGet, "/", dynamic.ThenFunc(app.home))
-	router.Handler(http.MethodGet, "/snippet/view/:id", dynamic.ThenFunc(app.snippetView))
+	router.Handler(http.MethodGet, "/snippet/view/:slug", dynamic.ThenFunc(app.snippetView))
+	router.Handler(http.MethodPost, "/snippet/unlock/:slug", dynamic.ThenFunc(app.snippetUnlockPost))
+	router.Handler(http.MethodPost, "/snippet/reveal/:slug", dynamic.ThenFunc(app.snippetRevealPost))
+	router.Handler(http.MethodGet, "/snippet/raw/:slug", dynamic.ThenFunc(app.snippetRaw))
+	router.Handler(http.MethodGet, "/snippet/download/:slug", dynamic.ThenFunc(app.snippetDownload))
+	router.Handler(http.MethodGet, "/snippet/view/:slug/history", dynamic.ThenFunc(app.snippetHistory))
+	router.Handler(http.MethodGet, "/snippet/view/:slug/history/:version", dynamic.ThenFunc(app.snippetRevision))
+	router.Handler(http.MethodGet, "/snippet/view/:slug/diff", dynamic.ThenFunc(app.snippetDiff))
+	router.Handler(http.MethodGet, "/snippets", dynamic.ThenFunc(app.snippetArchive))
+	router.Handler(http.MethodGet, "/tag/:name", dynamic.ThenFunc(app.snippetTag))
+	router.Handler(http.MethodGet, "/tags", dynamic.ThenFunc(app.tagCloud))
+	router.Handler(http.MethodGet, "/search", dynamic.ThenFunc(app.search))
 	router.Handler(http.MethodGet, "/user/signup", dynamic.ThenFunc(app.userSignup))
 	router.Handler(http.MethodPost, "/user/signup", dynamic.ThenFunc(app.userSignupPost))
 	router.Handler(http.MethodGet, "/user/login", dynamic.ThenFunc(app.userLogin))
@@ -108,15 +257,79 @@ func (app *application) routes() http.Handler {
 	// PROTECTED middleware for authenticated session control.
 	router.Handler(http.MethodGet, "/snippet/create", protected.ThenFunc(app.snippetCreate))
 	router.Handler(http.MethodPost, "/snippet/create", protected.ThenFunc(app.snippetCreatePost))
+	router.Handler(http.MethodGet, "/snippet/mine", protected.ThenFunc(app.snippetMine))
+	router.Handler(http.MethodGet, "/snippet/edit/:slug", protected.ThenFunc(app.snippetEdit))
+	router.Handler(http.MethodPost, "/snippet/edit/:slug", protected.ThenFunc(app.snippetEditPost))
+	router.Handler(http.MethodPost, "/snippet/delete/:slug", protected.ThenFunc(app.snippetDeletePost))
+	router.Handler(http.MethodGet, "/snippet/trash", protected.ThenFunc(app.snippetTrash))
+	router.Handler(http.MethodPost, "/snippet/restore/:slug", protected.ThenFunc(app.snippetRestorePost))
+	router.Handler(http.MethodGet, "/user/settings", protected.ThenFunc(app.userSettings))
+	router.Handler(http.MethodPost, "/user/tokens", protected.ThenFunc(app.userTokenCreatePost))
+	router.Handler(http.MethodPost, "/user/tokens/revoke/:id", protected.ThenFunc(app.userTokenRevokePost))
 	router.Handler(http.MethodPost, "/user/logout", protected.ThenFunc(app.userLogoutPost))
 	
+	// API ROUTES - JSON in and out, for tools rather than
+	// browsers
+
+	// The API doesn't use sessions, so it has no need
+	// for the DYNAMIC middleware or CSRF protection.
+	// Clients authenticate on every request with an API
+	// token instead. Routes with a scope need a token
+	// with that scope. Every API route is listed in
+	// apiRoutes(), so it is also in the OpenAPI document.
+	api := alice.New(app.apiAuthenticate)
+
+	for _, route := range app.apiRoutes() {
+		chain := api
+		if route.scope != "" {
+			chain = api.Append(app.apiRequireAuthentication, app.apiRequireScope(route.scope))
+		}
+		router.Handler(route.method, route.path, chain.ThenFunc(route.handler))
+	}
+
 	// Create a middleware chain containing the "standard"
 	// middleware which will be sent for every request
 	// the application receives. Alice manages middleware 
 	// chains.
-	standard := alice.New(app.recoverPanic, app.logRequest, secureHeaders)
+	// realClient comes first, so everything after it sees
+	// the real client address and scheme of requests
+	// from trusted proxies.
+	standard := alice.New(app.realClient, app.recoverPanic, app.logRequest, app.secureHeaders)
 
 	// Return the 'standard' middleware followed
 	// by the servermux.
 	return standard.Then(router)
-}
\ No newline at end of file
+}
+
+// apiRoute defines a type to hold a route of the JSON
+// API.
+// Contains:
+//	1. method, path - matched by the router
+//	2. handler - handles the route
+//	3. scope - the scope the API token must have, or
+//						 empty if no token is needed
+type apiRoute struct {
+	method	string
+	path		string
+	handler	http.HandlerFunc
+	scope		string
+}
+
+/*
+	apiRoutes function lists every route of the JSON
+	API. Each one must be described in apiOperations for
+	the OpenAPI document, which the tests check.
+*/
+func (app *application) apiRoutes() []apiRoute {
+	return []apiRoute{
+		{http.MethodGet, "/api/openapi.json", app.openAPI, ""},
+		{http.MethodGet, "/api/v1/snippets", app.apiSnippetList, ""},
+		{http.MethodPost, "/api/v1/snippets", app.apiSnippetCreate, models.ScopeWrite},
+		{http.MethodGet, "/api/v1/snippets/:slug", app.apiSnippetGet, ""},
+		{http.MethodPost, "/api/v1/snippets/:slug/reveal", app.apiSnippetReveal, ""},
+		{http.MethodPut, "/api/v1/snippets/:slug", app.apiSnippetUpdate, models.ScopeWrite},
+		{http.MethodDelete, "/api/v1/snippets/:slug", app.apiSnippetDelete, models.ScopeWrite},
+		{http.MethodPost, "/api/v1/paste", app.apiPaste, models.ScopeWrite},
+		{http.MethodGet, "/api/v1/user", app.apiUser, models.ScopeRead},
+	}
+}
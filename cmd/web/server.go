@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+/*
+	serve function runs the server until it fails
+	or the process is sent SIGINT or SIGTERM. On a
+	signal the server stops accepting connections and
+	waits for requests in progress to finish, which has
+	to happen within the shutdown timeout, otherwise an
+	error is returned. However serve returns, the
+	background goroutines, like the sweeper, are stopped
+	by closing done and waited for first, so the caller
+	can close the database. nil is only returned once
+	everything has stopped cleanly.
+*/
+func (app *application) serve(srv *http.Server, done chan struct{}) error {
+	// Stop the background goroutines and wait for them
+	// on every way out, including errors, so none of
+	// them are left using the database
+	defer func() {
+		close(done)
+		app.background.Wait()
+	}()
+
+	// Receives the result of the shutdown, once a signal
+	// has started one
+	shutdownErr := make(chan error)
+
+	// Catch SIGINT (Ctrl+C) and SIGTERM, which is what
+	// service managers and container runtimes send to
+	// stop a process
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+
+	go func() {
+		s := <-quit
+
+		// Stop catching signals, so a second Ctrl+C kills
+		// the process if the shutdown is taking too long
+		signal.Stop(quit)
+
+		app.infoLog.Printf("Caught %s, shutting down (waiting up to %s)", s, app.config.shutdownTimeout)
+
+		ctx, cancel := context.WithTimeout(context.Background(), app.config.shutdownTimeout)
+		defer cancel()
+
+		// Shutdown() makes ListenAndServe() return
+		// http.ErrServerClosed straight away, then waits for
+		// the open connections to finish their requests
+		err := srv.Shutdown(ctx)
+		if err != nil {
+			err = fmt.Errorf("draining requests: %w", err)
+		}
+		shutdownErr <- err
+	}()
+
+	// Start the server. Behind a reverse proxy which
+	// handles TLS, serve plain HTTP. Otherwise start the
+	// HTTPS server. The certificate comes from the
+	// server's TLSConfig, made by newTLSConfig(), so no
+	// files are passed in.
+	var err error
+	if app.config.plainHTTP {
+		app.infoLog.Printf("Starting plain HTTP server on port %s", srv.Addr)
+		err = srv.ListenAndServe()
+	} else {
+		if app.config.devTLS {
+			app.infoLog.Printf("Using a self-signed certificate for %s", app.config.certHosts.String())
+		}
+		app.infoLog.Printf("Starting server on port %s", srv.Addr)
+		err = srv.ListenAndServeTLS("", "")
+	}
+	if !errors.Is(err, http.ErrServerClosed) {
+		// The server failed rather than being shut down, so
+		// there are no requests to drain
+		return err
+	}
+
+	// Wait for the shutdown to finish
+	err = <-shutdownErr
+	if err != nil {
+		return err
+	}
+
+	app.infoLog.Print("Stopped server")
+	return nil
+}
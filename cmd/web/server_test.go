@@ -0,0 +1,38 @@
+package main
+
+import (
+	"io"
+	"log"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestServeStopsBackground(t *testing.T) {
+	app := &application{
+		errorLog:	log.New(io.Discard, "", 0),
+		infoLog:	log.New(io.Discard, "", 0),
+	}
+
+	// A background task which only finishes once done is
+	// closed, like the sweeper
+	done := make(chan struct{})
+	stopped := false
+	app.background.Add(1)
+	go func() {
+		defer app.background.Done()
+		<-done
+		time.Sleep(10 * time.Millisecond)
+		stopped = true
+	}()
+
+	// No TLS certificate is configured, so the server
+	// fails to start
+	err := app.serve(&http.Server{Addr: "127.0.0.1:0"}, done)
+	if err == nil {
+		t.Fatal("got no error, want the server to fail")
+	}
+	if !stopped {
+		t.Error("serve returned before the background task stopped")
+	}
+}
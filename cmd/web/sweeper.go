@@ -0,0 +1,107 @@
+package main
+
+import (
+	"runtime/debug"
+	"time"
+)
+
+/*
+	startSweeper function starts a background goroutine
+	which permanently removes expired snippets, snippets
+	which have been in the trash too long, and expired
+	sessions from the database. It runs once straight
+	away, then every sweep interval, until the done
+	channel is closed. The goroutine is tracked by the
+	application's background WaitGroup, so callers can
+	wait for a sweep in progress to finish.
+*/
+func (app *application) startSweeper(done <-chan struct{}) {
+	app.background.Add(1)
+
+	go func() {
+		defer app.background.Done()
+
+		ticker := time.NewTicker(app.config.sweep.interval)
+		defer ticker.Stop()
+
+		for {
+			app.safeSweep()
+
+			select {
+			case <-ticker.C:
+			case <-done:
+				return
+			}
+		}
+	}()
+}
+
+/*
+	safeSweep function runs a sweep, recovering from
+	any panic so a bad sweep is logged rather than
+	bringing down the whole application, and the next
+	sweep still runs.
+*/
+func (app *application) safeSweep() {
+	defer func() {
+		if err := recover(); err != nil {
+			app.errorLog.Printf("sweeper: %s\n%s", err, debug.Stack())
+		}
+	}()
+
+	app.sweep()
+}
+
+/*
+	sweep function removes everything that has expired,
+	in batches of the configured size so the database is
+	never locked for long, and logs how many rows were
+	removed, if any.
+*/
+func (app *application) sweep() {
+	// Remove expired snippets, one batch at a time,
+	// until a batch comes back short
+	snippets, err := sweepBatches(app.snippets.DeleteExpired, app.config.sweep.batch)
+	if err != nil {
+		app.errorLog.Printf("sweeper: deleting expired snippets: %s", err)
+	}
+
+	// Remove snippets which have outlived the trash
+	trashed, err := app.snippets.Purge()
+	if err != nil {
+		app.errorLog.Printf("sweeper: purging trash: %s", err)
+	}
+
+	// Remove expired sessions the session store
+	// hasn't cleaned up yet
+	sessions, err := sweepBatches(app.sessions.DeleteExpired, app.config.sweep.batch)
+	if err != nil {
+		app.errorLog.Printf("sweeper: deleting expired sessions: %s", err)
+	}
+
+	// Only log sweeps which removed something, so a quiet
+	// server's log isn't filled with empty sweeps
+	if snippets+trashed+sessions == 0 {
+		return
+	}
+	app.infoLog.Printf(
+		"Sweeper purged %d expired snippets, %d trashed snippets and %d expired sessions",
+		snippets, trashed, sessions,
+	)
+}
+
+/*
+	sweepBatches function calls deleteBatch until it
+	removes fewer rows than the batch size, and returns
+	the total number of rows removed.
+*/
+func sweepBatches(deleteBatch func(limit int) (int, error), batch int) (int, error) {
+	total := 0
+	for {
+		n, err := deleteBatch(batch)
+		total += n
+		if err != nil || n < batch {
+			return total, err
+		}
+	}
+}
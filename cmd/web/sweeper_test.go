@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"log"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/robwestbrook/snippetbox/internal/models"
+)
+
+func TestSweepBatches(t *testing.T) {
+	errBatch := errors.New("batch failed")
+
+	// Each test deletes the rows it is given, batch by
+	// batch, with an error from the batch failAt if set
+	tests := []struct {
+		name				string
+		rows				int
+		failAt			int
+		wantTotal		int
+		wantBatches	int
+		wantErr			error
+	}{
+		{"Nothing to delete", 0, 0, 0, 1, nil},
+		{"Part of a batch", 3, 0, 3, 1, nil},
+		{"Exactly one batch", 5, 0, 5, 2, nil},
+		{"Several batches", 12, 0, 12, 3, nil},
+		{"Failed batch", 12, 2, 5, 2, errBatch},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows, batches := tt.rows, 0
+
+			deleteBatch := func(limit int) (int, error) {
+				batches++
+				if batches == tt.failAt {
+					return 0, errBatch
+				}
+				n := limit
+				if rows < n {
+					n = rows
+				}
+				rows -= n
+				return n, nil
+			}
+
+			total, err := sweepBatches(deleteBatch, 5)
+			if total != tt.wantTotal || batches != tt.wantBatches || !errors.Is(err, tt.wantErr) {
+				t.Errorf("got %d rows in %d batches and error %v, want %d rows in %d batches and error %v",
+					total, batches, err, tt.wantTotal, tt.wantBatches, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSweeperPanic(t *testing.T) {
+	var errorLog bytes.Buffer
+
+	// Models without a database panic on every sweep
+	app := &application{
+		errorLog:	log.New(&errorLog, "", 0),
+		infoLog:	log.New(io.Discard, "", 0),
+		snippets:	&models.SnippetModel{},
+		sessions:	&models.SessionModel{},
+	}
+	app.config.sweep.interval = 10 * time.Millisecond
+	app.config.sweep.batch = 500
+
+	done := make(chan struct{})
+	app.startSweeper(done)
+	time.Sleep(100 * time.Millisecond)
+	close(done)
+	app.background.Wait()
+
+	// The sweeper carries on after a panic
+	if n := strings.Count(errorLog.String(), "sweeper: "); n < 2 {
+		t.Errorf("got %d sweeps logged, want the sweeper to keep going after a panic", n)
+	}
+}
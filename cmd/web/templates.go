@@ -2,9 +2,12 @@ package main
 
 import (
 	"html/template"
+	"net/url"
 	"path/filepath"
+	"strings"
 	"time"
 
+	"github.com/robwestbrook/snippetbox/internal/diff"
 	"github.com/robwestbrook/snippetbox/internal/models"
 )
 
@@ -17,6 +20,22 @@ import (
 //	5. Flash - holds any flash message generated
 // 	6. IsAuthenticated - holds true or false for authenticated users
 //	7. CSRFToken - Adds a CSRFToken
+//	8. IsOwner - holds true if the logged in user owns the snippet
+//	9. Revision - holds a single revision of a snippet
+//	10. Revisions - a slice of a snippet's revisions
+//	11. Diff - holds the differences between two revisions
+//	12. Query - holds the words being searched for
+//	13. Results - a slice of snippets matching a search
+//	14. PrevPage, NextPage - page numbers of the pages
+//			either side of the current one, 0 if there isn't one
+//	15. Page - holds a page of snippets and links to the pages around it
+//	16. Sort - holds the order snippets are listed in
+//	17. Tag - holds the tag snippets are listed with
+//	18. Tags - a slice of tags with their snippet counts
+//	19. Tokens - a slice of the logged in user's API tokens
+//	20. NewToken - holds an API token which has just been created
+//	21. Burned - holds true if the snippet was deleted as it was shown
+//	22. Expiries - the expiry options the snippet forms offer
 type templateData struct {
 	CurrentYear			int
 	Snippet					*models.Snippet
@@ -25,6 +44,33 @@ type templateData struct {
 	Flash						string
 	IsAuthenticated	bool
 	CSRFToken				string
+	IsOwner					bool
+	Revision				*models.Revision
+	Revisions				[]*models.Revision
+	Diff						*diffView
+	Query						string
+	Results					[]*models.SearchResult
+	PrevPage				int
+	NextPage				int
+	Page						*models.Page
+	Sort						string
+	Tag							string
+	Tags						[]*models.Tag
+	Tokens					[]*models.Token
+	NewToken				string
+	Burned					bool
+	Expiries				[]expiryOption
+}
+
+// diffView struct holds the two revisions being
+// compared on the diff page, the hunks of changed
+// lines between them, and whether to show them side
+// by side. From is nil when comparing against nothing.
+type diffView struct {
+	From	*models.Revision
+	To		*models.Revision
+	Hunks	[]diff.Hunk
+	Split	bool
 }
 
 /*
@@ -40,6 +86,57 @@ func humanDate(t time.Time) string {
 	return t.UTC().Format("02 Jan 2006 at 15:04")
 }
 
+/*
+	expiryDate function returns when a snippet expires,
+	formatted by humanDate, or "Never" if it doesn't.
+*/
+func expiryDate(s *models.Snippet) string {
+	if s.NeverExpires() {
+		return "Never"
+	}
+	return humanDate(s.Expires)
+}
+
+/*
+	highlight function HTML escapes text from a search
+	result, then swaps the markers around the matching
+	words for <mark> elements.
+*/
+func highlight(s string) template.HTML {
+	s = template.HTMLEscapeString(s)
+	s = strings.ReplaceAll(s, models.HighlightStart, "<mark>")
+	s = strings.ReplaceAll(s, models.HighlightEnd, "</mark>")
+	return template.HTML(s)
+}
+
+/*
+	tagURL function returns the URL of the page listing
+	the snippets with a tag. Characters like "#" which
+	are allowed in tags are escaped.
+*/
+func tagURL(tag string) string {
+	return "/tag/" + url.PathEscape(tag)
+}
+
+/*
+	tagSize function returns a size from 1 to 5 for a
+	tag in the tag cloud, depending on how many snippets
+	it is on compared with the most used tag in tags.
+*/
+func tagSize(tag *models.Tag, tags []*models.Tag) int {
+	most := 0
+	for _, t := range tags {
+		if t.Count > most {
+			most = t.Count
+		}
+	}
+	if most == 0 {
+		return 1
+	}
+
+	return 1 + tag.Count*4/most
+}
+
 /*
 	Initialize a template.FuncMap object and store in
 	a global variable. This is a string-keyed map which
@@ -50,6 +147,13 @@ func humanDate(t time.Time) string {
 */
 var functions = template.FuncMap{
 	"humanDate": humanDate,
+	"expiryDate": expiryDate,
+	"highlight": highlight,
+	"tagURL":    tagURL,
+	"tagSize":   tagSize,
+	"highlightCode": highlightCode,
+	"languages": func() []language { return languages },
+	"languageName": func(key string) string { return lookupLanguage(key).Name },
 }
 
 /*
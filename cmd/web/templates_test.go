@@ -1,8 +1,11 @@
 package main
 
 import (
+	"strings"
 	"testing"
 	"time"
+
+	"github.com/robwestbrook/snippetbox/internal/models"
 )
 
 func TestHumanDate(t *testing.T) {
@@ -49,4 +52,111 @@ func TestHumanDate(t *testing.T) {
 			}
 		})
 	}
-}
\ No newline at end of file
+}
+func TestHighlight(t *testing.T) {
+	// Matching words are marked up, and everything
+	// else in the search result is HTML escaped.
+	tests := []struct {
+		name string
+		s    string
+		want string
+	}{
+		{
+			name: "Match",
+			s:    "an " + models.HighlightStart + "old" + models.HighlightEnd + " pond",
+			want: "an <mark>old</mark> pond",
+		},
+		{
+			name: "No match",
+			s:    "an old pond",
+			want: "an old pond",
+		},
+		{
+			name: "Escaped",
+			s:    models.HighlightStart + "<script>" + models.HighlightEnd + " & more",
+			want: "<mark>&lt;script&gt;</mark> &amp; more",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := string(highlight(tt.s))
+
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTagSize(t *testing.T) {
+	// The most used tag is the biggest, and rarely used
+	// tags are the smallest.
+	tags := []*models.Tag{
+		{Name: "go", Count: 20},
+		{Name: "sql", Count: 10},
+		{Name: "c#", Count: 1},
+	}
+
+	want := []int{5, 3, 1}
+
+	for i, tag := range tags {
+		if got := tagSize(tag, tags); got != want[i] {
+			t.Errorf("%s: got %d, want %d", tag.Name, got, want[i])
+		}
+	}
+}
+
+func TestParseTags(t *testing.T) {
+	got := strings.Join(parseTags(" Go, sql,,go , C# "), "|")
+	want := "go|sql|c#"
+
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestHighlightCode(t *testing.T) {
+	// Code is marked up with classes rather than inline
+	// styles, and HTML in it is escaped.
+	got, err := highlightCode("go", "func main() { x := \"<b>\" }")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{`class="kd"`, "&lt;b&gt;"} {
+		if !strings.Contains(string(got), want) {
+			t.Errorf("got %q, want it to contain %q", got, want)
+		}
+	}
+	if strings.Contains(string(got), "style=") {
+		t.Errorf("got %q, want no inline styles", got)
+	}
+}
+
+func TestDetectLanguage(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{
+			name:    "Shebang",
+			content: "#!/bin/bash\necho hello",
+			want:    "bash",
+		},
+		{
+			name:    "Unknown",
+			content: "Just some notes.",
+			want:    plainText,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := detectLanguage(tt.content); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
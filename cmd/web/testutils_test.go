@@ -0,0 +1,229 @@
+package main
+
+import (
+	"bytes"
+	"database/sql"
+	"errors"
+	"flag"
+	"html"
+	"io"
+	"log"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/alexedwards/scs/v2"
+	"github.com/go-playground/form/v4"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/robwestbrook/snippetbox/internal/migrations"
+	"github.com/robwestbrook/snippetbox/internal/models"
+)
+
+/*
+	newTestApplication function returns an application
+	for tests, with its own migrated database in a
+	temporary directory, sessions kept in memory, and
+	the default settings. The database needs SQLite's
+	FTS5 extension, so the tests using it are skipped
+	unless run with -tags sqlite_fts5.
+*/
+func newTestApplication(t *testing.T) *application {
+	t.Helper()
+
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "test.db")+"?_busy_timeout=5000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		db.Close()
+	})
+
+	_, err = migrations.Up(db)
+	if errors.Is(err, migrations.ErrNoFTS5) {
+		t.Skip("SQLite was built without FTS5; run the tests with make test or -tags sqlite_fts5")
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The templates are found relative to the root of
+	// the repository, where the server is run from
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.Chdir("../..")
+	if err != nil {
+		t.Fatal(err)
+	}
+	templateCache, err := newTemplateCache()
+	os.Chdir(wd)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Take the default settings from the flags
+	var cfg config
+	defineFlags(flag.NewFlagSet("test", flag.ContinueOnError), &cfg)
+
+	sessionManager := scs.New()
+	sessionManager.Lifetime = 12 * time.Hour
+	sessionManager.Cookie.Secure = true
+
+	return &application{
+		errorLog:				log.New(io.Discard, "", 0),
+		infoLog:				log.New(io.Discard, "", 0),
+		snippets:				&models.SnippetModel{DB: db},
+		users:					&models.UserModel{DB: db},
+		templateCache:	templateCache,
+		formDecoder:		form.NewDecoder(),
+		sessionManager:	sessionManager,
+		sessions:				&models.SessionModel{DB: db},
+		config:					cfg,
+		unlocks:				newAttemptLimiter(unlockAttempts, unlockWindow),
+	}
+}
+
+// testServer defines a type which wraps an
+// httptest.Server, with a client that keeps cookies
+// between requests and doesn't follow redirects.
+type testServer struct {
+	*httptest.Server
+}
+
+/*
+	newTestServer function starts an HTTPS test server
+	for h, stopped when the test finishes.
+*/
+func newTestServer(t *testing.T, h http.Handler) *testServer {
+	ts := httptest.NewTLSServer(h)
+	t.Cleanup(ts.Close)
+
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts.Client().Jar = jar
+
+	// Return redirects rather than following them, so
+	// they can be checked
+	ts.Client().CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+
+	return &testServer{ts}
+}
+
+/*
+	do function sends a request to the test server, with
+	the headers given, and returns the response's status
+	code, headers and body.
+*/
+func (ts *testServer) do(t *testing.T, method string, path string, body io.Reader, header http.Header) (int, http.Header, string) {
+	t.Helper()
+
+	req, err := http.NewRequest(method, ts.URL+path, body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+
+	rs, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rs.Body.Close()
+
+	b, err := io.ReadAll(rs.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return rs.StatusCode, rs.Header, string(bytes.TrimSpace(b))
+}
+
+/*
+	get function sends a GET request to the test server.
+*/
+func (ts *testServer) get(t *testing.T, path string) (int, http.Header, string) {
+	t.Helper()
+	return ts.do(t, http.MethodGet, path, nil, nil)
+}
+
+/*
+	postForm function sends a form to the test server,
+	with the CSRF token from the page at tokenPath.
+*/
+func (ts *testServer) postForm(t *testing.T, path string, tokenPath string, form url.Values) (int, http.Header, string) {
+	t.Helper()
+
+	_, _, page := ts.get(t, tokenPath)
+	form.Set("csrf_token", extractCSRFToken(t, page))
+
+	header := http.Header{"Content-Type": {"application/x-www-form-urlencoded"}}
+	return ts.do(t, http.MethodPost, path, bytes.NewBufferString(form.Encode()), header)
+}
+
+/*
+	login function logs the test server's client in as
+	the user with the given email address, whose
+	password is "pa55word".
+*/
+func (ts *testServer) login(t *testing.T, email string) {
+	t.Helper()
+
+	code, _, _ := ts.postForm(t, "/user/login", "/user/login", url.Values{
+		"email":    {email},
+		"password": {"pa55word"},
+	})
+	if code != http.StatusSeeOther {
+		t.Fatalf("got status %d logging in as %s, want %d", code, email, http.StatusSeeOther)
+	}
+}
+
+// csrfTokenRX matches the CSRF token in a form.
+var csrfTokenRX = regexp.MustCompile(`<input type=['"]hidden['"] name=['"]csrf_token['"] value=['"](.+?)['"]`)
+
+/*
+	extractCSRFToken function returns the CSRF token in
+	the form on a page.
+*/
+func extractCSRFToken(t *testing.T, body string) string {
+	t.Helper()
+
+	matches := csrfTokenRX.FindStringSubmatch(body)
+	if len(matches) < 2 {
+		t.Fatal("no CSRF token found in body")
+	}
+
+	return html.UnescapeString(matches[1])
+}
+
+/*
+	newTestUser function adds a user named name to the
+	application's database, with the email address
+	name@example.com and the password "pa55word", and
+	returns their ID.
+*/
+func newTestUser(t *testing.T, app *application, name string) int {
+	t.Helper()
+
+	err := app.users.Insert(name, name+"@example.com", "pa55word")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	id, err := app.users.Authenticate(name+"@example.com", "pa55word")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return id
+}
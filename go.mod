@@ -3,6 +3,7 @@ module github.com/robwestbrook/snippetbox
 go 1.20
 
 require (
+	github.com/alecthomas/chroma/v2 v2.14.0
 	github.com/alexedwards/scs/sqlite3store v0.0.0-20231113091146-cef4b05350c8
 	github.com/alexedwards/scs/v2 v2.7.0
 	github.com/go-playground/form/v4 v4.2.1
@@ -12,3 +13,5 @@ require (
 	github.com/mattn/go-sqlite3 v1.14.19
 	golang.org/x/crypto v0.18.0
 )
+
+require github.com/dlclark/regexp2 v1.11.0 // indirect
@@ -0,0 +1,275 @@
+// Package diff compares two texts line by line.
+package diff
+
+import "strings"
+
+// Op describes what happened to a line going from the
+// old text to the new text.
+type Op int
+
+// The possible operations on a line.
+//  1. Equal - the line is in both texts
+//  2. Delete - the line is only in the old text
+//  3. Insert - the line is only in the new text
+const (
+	Equal Op = iota
+	Delete
+	Insert
+)
+
+// String returns the name of the operation, which is
+// used to style lines in templates.
+func (op Op) String() string {
+	switch op {
+	case Delete:
+		return "delete"
+	case Insert:
+		return "insert"
+	default:
+		return "equal"
+	}
+}
+
+// Symbol returns the character marking a line with
+// the operation in a unified diff.
+func (op Op) Symbol() string {
+	switch op {
+	case Delete:
+		return "-"
+	case Insert:
+		return "+"
+	default:
+		return " "
+	}
+}
+
+// Line is a single line of a diff. OldNumber and
+// NewNumber are the 1-based line numbers in the old
+// and new texts, and are 0 when the line isn't in
+// that text.
+type Line struct {
+	Op        Op
+	OldNumber int
+	NewNumber int
+	Text      string
+}
+
+// Hunk is a run of changed lines along with the
+// unchanged lines around them, like a hunk in the
+// output of "diff -u".
+type Hunk struct {
+	OldStart int
+	OldCount int
+	NewStart int
+	NewCount int
+	Lines    []Line
+}
+
+// Row pairs up the lines shown side by side. Either
+// side is nil when there is nothing to show there.
+type Row struct {
+	Old *Line
+	New *Line
+}
+
+// maxCells limits the size of the table used to find
+// the longest common subsequence of lines. Texts too
+// big to compare are shown as entirely replaced.
+const maxCells = 4 << 20
+
+// Lines compares a and b and returns every line of
+// both texts in order, marked as equal, deleted or
+// inserted.
+func Lines(a, b string) []Line {
+	before := splitLines(a)
+	after := splitLines(b)
+
+	// Lines at the start and end which haven't changed
+	// don't need to go into the comparison table.
+	prefix := 0
+	for prefix < len(before) && prefix < len(after) && before[prefix] == after[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(before)-prefix && suffix < len(after)-prefix &&
+		before[len(before)-1-suffix] == after[len(after)-1-suffix] {
+		suffix++
+	}
+
+	lines := make([]Line, 0, len(before)+len(after))
+	for i := 0; i < prefix; i++ {
+		lines = append(lines, Line{Op: Equal, OldNumber: i + 1, NewNumber: i + 1, Text: before[i]})
+	}
+
+	lines = appendMiddle(lines, before[prefix:len(before)-suffix], after[prefix:len(after)-suffix], prefix, prefix)
+
+	for i := suffix; i > 0; i-- {
+		o, n := len(before)-i, len(after)-i
+		lines = append(lines, Line{Op: Equal, OldNumber: o + 1, NewNumber: n + 1, Text: before[o]})
+	}
+
+	return lines
+}
+
+// appendMiddle appends the diff of before and after
+// to lines, using the longest common subsequence of the
+// two. oldOffset and newOffset are the number of lines
+// that come ahead of before and after in their texts.
+func appendMiddle(lines []Line, before, after []string, oldOffset, newOffset int) []Line {
+	n, m := len(before), len(after)
+
+	if n*m > maxCells {
+		for i := range before {
+			lines = append(lines, Line{Op: Delete, OldNumber: oldOffset + i + 1, Text: before[i]})
+		}
+		for j := range after {
+			lines = append(lines, Line{Op: Insert, NewNumber: newOffset + j + 1, Text: after[j]})
+		}
+		return lines
+	}
+
+	// lcs[i][j] holds the length of the longest common
+	// subsequence of before[i:] and after[j:].
+	lcs := make([][]int32, n+1)
+	for i := range lcs {
+		lcs[i] = make([]int32, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if before[i] == after[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	// Walk the table, preferring deletions over
+	// insertions so removed lines come first.
+	i, j := 0, 0
+	for i < n || j < m {
+		switch {
+		case i < n && j < m && before[i] == after[j]:
+			lines = append(lines, Line{Op: Equal, OldNumber: oldOffset + i + 1, NewNumber: newOffset + j + 1, Text: before[i]})
+			i++
+			j++
+		case i < n && (j == m || lcs[i+1][j] >= lcs[i][j+1]):
+			lines = append(lines, Line{Op: Delete, OldNumber: oldOffset + i + 1, Text: before[i]})
+			i++
+		default:
+			lines = append(lines, Line{Op: Insert, NewNumber: newOffset + j + 1, Text: after[j]})
+			j++
+		}
+	}
+
+	return lines
+}
+
+// Hunks groups the changed lines of a diff into hunks,
+// keeping up to context unchanged lines either side of
+// each change. Changes closer together than twice the
+// context share a hunk. A diff with no changes has no
+// hunks.
+func Hunks(lines []Line, context int) []Hunk {
+	var hunks []Hunk
+
+	for i := 0; i < len(lines); {
+		if lines[i].Op == Equal {
+			i++
+			continue
+		}
+
+		// Extend the hunk until the next change is too
+		// far away.
+		start := i - context
+		if start < 0 {
+			start = 0
+		}
+		end := i
+		for k := i; k < len(lines) && k-end <= 2*context; k++ {
+			if lines[k].Op != Equal {
+				end = k
+			}
+		}
+		end += context + 1
+		if end > len(lines) {
+			end = len(lines)
+		}
+
+		hunks = append(hunks, newHunk(lines[start:end]))
+		i = end
+	}
+
+	return hunks
+}
+
+// newHunk works out the line ranges covered by lines.
+func newHunk(lines []Line) Hunk {
+	h := Hunk{Lines: lines}
+	for _, l := range lines {
+		if l.Op != Insert {
+			if h.OldStart == 0 {
+				h.OldStart = l.OldNumber
+			}
+			h.OldCount++
+		}
+		if l.Op != Delete {
+			if h.NewStart == 0 {
+				h.NewStart = l.NewNumber
+			}
+			h.NewCount++
+		}
+	}
+	return h
+}
+
+// Rows lays out the lines of the hunk side by side.
+// Unchanged lines appear on both sides, and each run
+// of deleted lines is paired with the inserted lines
+// that replaced it.
+func (h Hunk) Rows() []Row {
+	var rows []Row
+
+	for i := 0; i < len(h.Lines); {
+		if h.Lines[i].Op == Equal {
+			rows = append(rows, Row{Old: &h.Lines[i], New: &h.Lines[i]})
+			i++
+			continue
+		}
+
+		var deleted, inserted []*Line
+		for ; i < len(h.Lines) && h.Lines[i].Op == Delete; i++ {
+			deleted = append(deleted, &h.Lines[i])
+		}
+		for ; i < len(h.Lines) && h.Lines[i].Op == Insert; i++ {
+			inserted = append(inserted, &h.Lines[i])
+		}
+
+		for k := 0; k < len(deleted) || k < len(inserted); k++ {
+			var row Row
+			if k < len(deleted) {
+				row.Old = deleted[k]
+			}
+			if k < len(inserted) {
+				row.New = inserted[k]
+			}
+			rows = append(rows, row)
+		}
+	}
+
+	return rows
+}
+
+// splitLines splits s into lines, ignoring the
+// difference between "\r\n" and "\n" line endings
+// and any final newline.
+func splitLines(s string) []string {
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	s = strings.TrimSuffix(s, "\n")
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, "\n")
+}
@@ -0,0 +1,150 @@
+package diff
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// render writes a diff out in the style of "diff -u",
+// without the hunk headers, so it is easy to compare.
+func render(lines []Line) string {
+	var b strings.Builder
+	for _, l := range lines {
+		switch l.Op {
+		case Equal:
+			fmt.Fprintf(&b, " %s\n", l.Text)
+		case Delete:
+			fmt.Fprintf(&b, "-%s\n", l.Text)
+		case Insert:
+			fmt.Fprintf(&b, "+%s\n", l.Text)
+		}
+	}
+	return b.String()
+}
+
+func TestLines(t *testing.T) {
+	// Create a slice of anonymous structs containing
+	// the test case name, the old and new texts, and
+	// the expected diff.
+	tests := []struct {
+		name string
+		a    string
+		b    string
+		want string
+	}{
+		{
+			name: "Unchanged",
+			a:    "one\ntwo\n",
+			b:    "one\ntwo",
+			want: " one\n two\n",
+		},
+		{
+			name: "Empty",
+			a:    "",
+			b:    "",
+			want: "",
+		},
+		{
+			name: "Added",
+			a:    "",
+			b:    "one\ntwo",
+			want: "+one\n+two\n",
+		},
+		{
+			name: "Changed middle",
+			a:    "one\ntwo\nthree",
+			b:    "one\n2\nthree",
+			want: " one\n-two\n+2\n three\n",
+		},
+		{
+			name: "Line endings",
+			a:    "one\r\ntwo\r\n",
+			b:    "one\ntwo\n",
+			want: " one\n two\n",
+		},
+		{
+			name: "Moved line",
+			a:    "a\nb\nc\nd",
+			b:    "b\nc\na\nd",
+			want: "-a\n b\n c\n+a\n d\n",
+		},
+	}
+
+	// Loop over test cases, running a sub-test for each
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := render(Lines(tt.a, tt.b))
+
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHunks(t *testing.T) {
+	// Ten numbered lines with the 2nd and 9th changed
+	// are too far apart to share a hunk with one line of
+	// context, but close enough with four.
+	var a, b []string
+	for i := 1; i <= 10; i++ {
+		a = append(a, fmt.Sprint(i))
+		b = append(b, fmt.Sprint(i))
+	}
+	b[1], b[8] = "two", "nine"
+	lines := Lines(strings.Join(a, "\n"), strings.Join(b, "\n"))
+
+	tests := []struct {
+		name    string
+		context int
+		want    []string
+	}{
+		{
+			name:    "Separate",
+			context: 1,
+			want:    []string{"-1,3 +1,3", "-8,3 +8,3"},
+		},
+		{
+			name:    "Merged",
+			context: 4,
+			want:    []string{"-1,10 +1,10"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, h := range Hunks(lines, tt.context) {
+				got = append(got, fmt.Sprintf("-%d,%d +%d,%d", h.OldStart, h.OldCount, h.NewStart, h.NewCount))
+			}
+
+			if strings.Join(got, " ") != strings.Join(tt.want, " ") {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRows(t *testing.T) {
+	// A replaced line sits beside the line it replaced,
+	// and an extra inserted line has nothing beside it.
+	h := Hunks(Lines("one\ntwo\nthree", "one\n2\n2.5\nthree"), 1)[0]
+
+	var got []string
+	for _, row := range h.Rows() {
+		left, right := "", ""
+		if row.Old != nil {
+			left = row.Old.Text
+		}
+		if row.New != nil {
+			right = row.New.Text
+		}
+		got = append(got, left+"|"+right)
+	}
+
+	want := "one|one two|2 |2.5 three|three"
+	if strings.Join(got, " ") != want {
+		t.Errorf("got %q, want %q", strings.Join(got, " "), want)
+	}
+}
@@ -0,0 +1,294 @@
+// Package migrations keeps the database schema up to
+// date. Each change to the schema is a numbered pair of
+// SQL files in the sql directory, embedded in the
+// binary:
+//
+//	NNNN_name.up.sql   - applies the change
+//	NNNN_name.down.sql - reverses the change
+//
+// The versions applied to a database are recorded in
+// its schema_migrations table.
+package migrations
+
+import (
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// files holds the embedded SQL migration files.
+//
+//go:embed sql/*.sql
+var files embed.FS
+
+// timeFormat matches the format the models package
+// uses for datetimes in SQLite, which are all in UTC.
+const timeFormat = "2006-01-02 15:04:05"
+
+// Migration is a single versioned change to the
+// database schema, with the SQL to apply and reverse
+// it.
+type Migration struct {
+	Version	int
+	Name		string
+	Up			string
+	Down		string
+}
+
+// Status describes a migration and whether it has been
+// applied to a database. Applied is the zero time for
+// migrations which are still pending.
+type Status struct {
+	Migration
+	Applied	time.Time
+}
+
+/*
+	All function returns every embedded migration,
+	ordered by version. An error is returned if a file
+	is misnamed, a version is used twice, or either half
+	of a pair is missing.
+*/
+func All() ([]Migration, error) {
+	names, err := fs.Glob(files, "sql/*.sql")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int]*Migration{}
+
+	for _, name := range names {
+		// Split "0001_create_snippets.up.sql" into its
+		// version, name and direction
+		base := strings.TrimSuffix(path.Base(name), ".sql")
+		direction := path.Ext(base)
+		base = strings.TrimSuffix(base, direction)
+
+		number, title, ok := strings.Cut(base, "_")
+		version, err := strconv.Atoi(number)
+		if !ok || err != nil || version < 1 {
+			return nil, fmt.Errorf("migrations: badly named file %s", name)
+		}
+
+		contents, err := files.ReadFile(name)
+		if err != nil {
+			return nil, err
+		}
+
+		m, exists := byVersion[version]
+		if !exists {
+			m = &Migration{Version: version, Name: title}
+			byVersion[version] = m
+		} else if m.Name != title {
+			return nil, fmt.Errorf("migrations: version %d is used by %s and %s", version, m.Name, title)
+		}
+
+		switch direction {
+		case ".up":
+			m.Up = string(contents)
+		case ".down":
+			m.Down = string(contents)
+		default:
+			return nil, fmt.Errorf("migrations: badly named file %s", name)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" || m.Down == "" {
+			return nil, fmt.Errorf("migrations: %04d_%s needs both an up and a down file", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
+
+// ErrNoFTS5 is returned when SQLite doesn't include the
+// FTS5 full-text search extension, which the snippets_fts
+// table and snippet search need.
+var ErrNoFTS5 = errors.New("migrations: SQLite was built without the FTS5 extension, which snippet search needs; build with -tags sqlite_fts5")
+
+/*
+	CheckFTS5 function returns ErrNoFTS5 unless the
+	SQLite the database is opened with includes the FTS5
+	extension. The go-sqlite3 driver only includes it
+	when built with the sqlite_fts5 tag, and without it
+	the search migration, and searching, fail with the
+	less helpful "no such module: fts5".
+*/
+func CheckFTS5(db *sql.DB) error {
+	var fts5 bool
+	err := db.QueryRow(`SELECT sqlite_compileoption_used('ENABLE_FTS5')`).Scan(&fts5)
+	if err != nil {
+		return err
+	}
+	if !fts5 {
+		return ErrNoFTS5
+	}
+	return nil
+}
+
+/*
+	Up function applies every pending migration to the
+	database in version order, and returns the migrations
+	applied. Each migration runs in its own transaction,
+	so a failing migration leaves the database at the
+	last version which succeeded. Nothing is applied if
+	SQLite doesn't include FTS5.
+*/
+func Up(db *sql.DB) ([]Migration, error) {
+	err := CheckFTS5(db)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses, err := Statuses(db)
+	if err != nil {
+		return nil, err
+	}
+
+	var applied []Migration
+
+	for _, s := range statuses {
+		if !s.Applied.IsZero() {
+			continue
+		}
+
+		err = run(db, s.Up,
+			`INSERT INTO schema_migrations (version, name, applied) VALUES (?, ?, ?)`,
+			s.Version, s.Name, time.Now().UTC().Format(timeFormat),
+		)
+		if err != nil {
+			return applied, fmt.Errorf("migrations: applying %04d_%s: %w", s.Version, s.Name, err)
+		}
+
+		applied = append(applied, s.Migration)
+	}
+
+	return applied, nil
+}
+
+/*
+	Down function reverses the most recently applied
+	migrations, up to steps of them, and returns the
+	migrations reversed, latest first.
+*/
+func Down(db *sql.DB, steps int) ([]Migration, error) {
+	statuses, err := Statuses(db)
+	if err != nil {
+		return nil, err
+	}
+
+	var reversed []Migration
+
+	for i := len(statuses) - 1; i >= 0 && len(reversed) < steps; i-- {
+		s := statuses[i]
+		if s.Applied.IsZero() {
+			continue
+		}
+
+		err = run(db, s.Down,
+			`DELETE FROM schema_migrations WHERE version = ?`,
+			s.Version,
+		)
+		if err != nil {
+			return reversed, fmt.Errorf("migrations: reversing %04d_%s: %w", s.Version, s.Name, err)
+		}
+
+		reversed = append(reversed, s.Migration)
+	}
+
+	return reversed, nil
+}
+
+/*
+	Statuses function returns every migration, in
+	version order, along with when it was applied to the
+	database. The schema_migrations table is created if
+	it doesn't exist yet.
+*/
+func Statuses(db *sql.DB) ([]Status, error) {
+	migrations, err := All()
+	if err != nil {
+		return nil, err
+	}
+
+	stmt := `
+		CREATE TABLE IF NOT EXISTS "schema_migrations" (
+			"version"	INTEGER NOT NULL,
+			"name"	TEXT NOT NULL,
+			"applied"	TEXT NOT NULL,
+			PRIMARY KEY("version")
+		)
+	`
+	_, err = db.Exec(stmt)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.Query(`SELECT version, applied FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := map[int]time.Time{}
+	for rows.Next() {
+		var version int
+		var appliedTime string
+
+		err = rows.Scan(&version, &appliedTime)
+		if err != nil {
+			return nil, err
+		}
+
+		applied[version], _ = time.Parse(timeFormat, appliedTime)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	statuses := make([]Status, len(migrations))
+	for i, m := range migrations {
+		statuses[i] = Status{Migration: m, Applied: applied[m.Version]}
+	}
+
+	return statuses, nil
+}
+
+/*
+	run function executes the SQL script for a migration
+	and then the statement which records it in the
+	schema_migrations table, inside one transaction.
+*/
+func run(db *sql.DB, script string, record string, args ...any) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(script)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(record, args...)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,132 @@
+package migrations
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// newTestDB opens an empty in-memory database. The pool
+// is limited to one connection, because each connection
+// to ":memory:" gets its own separate database.
+func newTestDB(t *testing.T) *sql.DB {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.SetMaxOpenConns(1)
+
+	t.Cleanup(func() {
+		db.Close()
+	})
+
+	return db
+}
+
+func TestAll(t *testing.T) {
+	migrations, err := All()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Versions must run from 1 upwards with no gaps, so
+	// the order migrations run in is never in doubt.
+	for i, m := range migrations {
+		if m.Version != i+1 {
+			t.Errorf("got version %d for %s, want %d", m.Version, m.Name, i+1)
+		}
+	}
+}
+
+func TestUpAndDown(t *testing.T) {
+	db := newTestDB(t)
+
+	// The full-text search migration needs SQLite's
+	// FTS5 extension, which go-sqlite3 only includes
+	// when built with the sqlite_fts5 tag
+	err := CheckFTS5(db)
+	if errors.Is(err, ErrNoFTS5) {
+		t.Skip("SQLite was built without FTS5; run the tests with make test or -tags sqlite_fts5")
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	all, err := All()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Every migration is applied to an empty database
+	applied, err := Up(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(applied) != len(all) {
+		t.Fatalf("got %d migrations applied, want %d", len(applied), len(all))
+	}
+
+	// Running again has nothing left to do
+	applied, err = Up(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(applied) != 0 {
+		t.Errorf("got %d migrations applied again, want 0", len(applied))
+	}
+
+	// Every migration can be reversed, latest first
+	reversed, err := Down(db, len(all))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reversed) != len(all) || reversed[0].Version != all[len(all)-1].Version {
+		t.Fatalf("got %d migrations reversed, want %d latest first", len(reversed), len(all))
+	}
+
+	// Only the schema_migrations table is left behind
+	var tables int
+	err = db.QueryRow(`
+		SELECT COUNT(*) FROM sqlite_master
+		WHERE type = 'table' AND name NOT IN ('schema_migrations', 'sqlite_sequence')
+	`).Scan(&tables)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tables != 0 {
+		t.Errorf("got %d tables left after reversing, want 0", tables)
+	}
+
+	// And the whole schema can be built again
+	_, err = Up(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	statuses, err := Statuses(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range statuses {
+		if s.Applied.IsZero() {
+			t.Errorf("%04d_%s is still pending", s.Version, s.Name)
+		}
+	}
+}
+
+func TestUpWithoutFTS5(t *testing.T) {
+	db := newTestDB(t)
+
+	if CheckFTS5(db) == nil {
+		t.Skip("SQLite was built with FTS5")
+	}
+
+	// Nothing is applied, rather than stopping part way
+	// at the search migration
+	applied, err := Up(db)
+	if !errors.Is(err, ErrNoFTS5) || len(applied) != 0 {
+		t.Errorf("got %d migrations applied and error %v, want none and ErrNoFTS5", len(applied), err)
+	}
+}
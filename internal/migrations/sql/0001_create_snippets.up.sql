@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS "snippets" (
+	"id"	INTEGER NOT NULL,
+	"title"	TEXT NOT NULL,
+	"content"	TEXT NOT NULL,
+	"created"	TEXT NOT NULL,
+	"expires"	TEXT NOT NULL,
+	PRIMARY KEY("id" AUTOINCREMENT)
+);
+
+CREATE INDEX IF NOT EXISTS "idx_snippets_created" ON "snippets" (
+	"created"
+);
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS "sessions" (
+	"token"	TEXT,
+	"data"	BLOB NOT NULL,
+	"expiry"	REAL NOT NULL,
+	PRIMARY KEY("token")
+);
+
+CREATE INDEX IF NOT EXISTS "sessions_expiry_idx" ON "sessions" (
+	"expiry"
+);
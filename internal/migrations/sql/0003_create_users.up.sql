@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS "users" (
+	"id"	INTEGER NOT NULL,
+	"name"	TEXT NOT NULL,
+	"email"	TEXT NOT NULL UNIQUE,
+	"hashed_password"	TEXT NOT NULL,
+	"created"	TEXT NOT NULL,
+	PRIMARY KEY("id" AUTOINCREMENT)
+);
@@ -0,0 +1,3 @@
+DROP INDEX "idx_snippets_user_id";
+
+ALTER TABLE "snippets" DROP COLUMN "user_id";
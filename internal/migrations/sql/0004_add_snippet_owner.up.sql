@@ -0,0 +1,7 @@
+-- Snippets created before ownership was recorded keep
+-- a NULL user_id.
+ALTER TABLE "snippets" ADD COLUMN "user_id" INTEGER;
+
+CREATE INDEX "idx_snippets_user_id" ON "snippets" (
+	"user_id"
+);
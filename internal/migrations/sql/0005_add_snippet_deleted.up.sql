@@ -0,0 +1,3 @@
+-- The time a snippet was moved to the trash, or NULL
+-- if it isn't in the trash.
+ALTER TABLE "snippets" ADD COLUMN "deleted" TEXT;
@@ -0,0 +1,16 @@
+CREATE TABLE "snippet_revisions" (
+	"id"	INTEGER NOT NULL,
+	"snippet_id"	INTEGER NOT NULL,
+	"version"	INTEGER NOT NULL,
+	"title"	TEXT NOT NULL,
+	"content"	TEXT NOT NULL,
+	"created"	TEXT NOT NULL,
+	"user_id"	INTEGER,
+	PRIMARY KEY("id" AUTOINCREMENT),
+	UNIQUE("snippet_id","version")
+);
+
+-- Existing snippets start their history with their
+-- current title and content.
+INSERT INTO "snippet_revisions" ("snippet_id", "version", "title", "content", "created", "user_id")
+SELECT "id", 1, "title", "content", "created", "user_id" FROM "snippets";
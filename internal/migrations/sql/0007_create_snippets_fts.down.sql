@@ -0,0 +1,4 @@
+DROP TRIGGER "snippets_fts_update";
+DROP TRIGGER "snippets_fts_delete";
+DROP TRIGGER "snippets_fts_insert";
+DROP TABLE "snippets_fts";
@@ -0,0 +1,34 @@
+-- Full-text index of snippet titles and content. This
+-- needs SQLite's FTS5 extension, so the application
+-- must be built with the "sqlite_fts5" tag.
+--
+-- The index is an external content table, which reads
+-- the text from the snippets table itself rather than
+-- keeping a copy. The triggers below keep it in sync.
+CREATE VIRTUAL TABLE "snippets_fts" USING fts5(
+	"title",
+	"content",
+	content = 'snippets',
+	content_rowid = 'id',
+	tokenize = 'porter unicode61'
+);
+
+CREATE TRIGGER "snippets_fts_insert" AFTER INSERT ON "snippets" BEGIN
+	INSERT INTO "snippets_fts" ("rowid", "title", "content")
+	VALUES (new."id", new."title", new."content");
+END;
+
+CREATE TRIGGER "snippets_fts_delete" AFTER DELETE ON "snippets" BEGIN
+	INSERT INTO "snippets_fts" ("snippets_fts", "rowid", "title", "content")
+	VALUES ('delete', old."id", old."title", old."content");
+END;
+
+CREATE TRIGGER "snippets_fts_update" AFTER UPDATE OF "title", "content" ON "snippets" BEGIN
+	INSERT INTO "snippets_fts" ("snippets_fts", "rowid", "title", "content")
+	VALUES ('delete', old."id", old."title", old."content");
+	INSERT INTO "snippets_fts" ("rowid", "title", "content")
+	VALUES (new."id", new."title", new."content");
+END;
+
+-- Index the snippets which already exist.
+INSERT INTO "snippets_fts" ("snippets_fts") VALUES ('rebuild');
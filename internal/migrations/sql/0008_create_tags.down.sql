@@ -0,0 +1,2 @@
+DROP TABLE "snippet_tags";
+DROP TABLE "tags";
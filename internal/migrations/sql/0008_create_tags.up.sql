@@ -0,0 +1,17 @@
+CREATE TABLE "tags" (
+	"id"	INTEGER NOT NULL,
+	"name"	TEXT NOT NULL UNIQUE,
+	PRIMARY KEY("id" AUTOINCREMENT)
+);
+
+-- Links each snippet to its tags, and each tag to the
+-- snippets it is on.
+CREATE TABLE "snippet_tags" (
+	"snippet_id"	INTEGER NOT NULL,
+	"tag_id"	INTEGER NOT NULL,
+	PRIMARY KEY("snippet_id","tag_id")
+);
+
+CREATE INDEX "idx_snippet_tags_tag_id" ON "snippet_tags" (
+	"tag_id"
+);
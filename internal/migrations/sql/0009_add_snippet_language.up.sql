@@ -0,0 +1,4 @@
+-- The programming language a snippet is written in,
+-- used to highlight it. Existing snippets are treated
+-- as plain text.
+ALTER TABLE "snippets" ADD COLUMN "language" TEXT NOT NULL DEFAULT 'text';
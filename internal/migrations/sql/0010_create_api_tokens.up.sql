@@ -0,0 +1,17 @@
+-- Personal API tokens. Only the SHA-256 hash of each
+-- token is stored, so a leaked database can't be used
+-- to call the API.
+CREATE TABLE "api_tokens" (
+	"id"	INTEGER NOT NULL,
+	"user_id"	INTEGER NOT NULL,
+	"name"	TEXT NOT NULL,
+	"hash"	BLOB NOT NULL UNIQUE,
+	"scope"	TEXT NOT NULL,
+	"created"	TEXT NOT NULL,
+	"last_used"	TEXT,
+	PRIMARY KEY("id" AUTOINCREMENT)
+);
+
+CREATE INDEX "idx_api_tokens_user_id" ON "api_tokens" (
+	"user_id"
+);
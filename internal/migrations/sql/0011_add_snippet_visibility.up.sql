@@ -0,0 +1,5 @@
+-- Who can see a snippet: "public" snippets are listed
+-- everywhere, "unlisted" snippets are left out of every
+-- listing, and "private" snippets can only be seen by
+-- their owner. Existing snippets stay public.
+ALTER TABLE "snippets" ADD COLUMN "visibility" TEXT NOT NULL DEFAULT 'public';
@@ -0,0 +1,29 @@
+-- Every snippet now has a random slug, which is used in
+-- its URLs in place of the sequential ID. Snippets that
+-- already exist are marked as legacy, so their old
+-- numbered URLs can redirect to the slug. The slugs are
+-- built the same way as newSlug() builds them: a letter
+-- followed by 11 base62 characters.
+ALTER TABLE "snippets" ADD COLUMN "slug" TEXT;
+ALTER TABLE "snippets" ADD COLUMN "legacy" INTEGER NOT NULL DEFAULT 0;
+
+CREATE UNIQUE INDEX "idx_snippets_slug" ON "snippets" (
+	"slug"
+);
+
+UPDATE "snippets" SET "legacy" = 1;
+
+UPDATE "snippets" SET "slug" =
+	substr('ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz', 1 + abs(random() % 52), 1)
+	|| substr('0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz', 1 + abs(random() % 62), 1)
+	|| substr('0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz', 1 + abs(random() % 62), 1)
+	|| substr('0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz', 1 + abs(random() % 62), 1)
+	|| substr('0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz', 1 + abs(random() % 62), 1)
+	|| substr('0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz', 1 + abs(random() % 62), 1)
+	|| substr('0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz', 1 + abs(random() % 62), 1)
+	|| substr('0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz', 1 + abs(random() % 62), 1)
+	|| substr('0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz', 1 + abs(random() % 62), 1)
+	|| substr('0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz', 1 + abs(random() % 62), 1)
+	|| substr('0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz', 1 + abs(random() % 62), 1)
+	|| substr('0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz', 1 + abs(random() % 62), 1)
+WHERE "slug" IS NULL;
@@ -0,0 +1,4 @@
+-- The bcrypt hash of a snippet's password, for snippets
+-- which can only be viewed once the password has been
+-- entered. Snippets without a password have NULL.
+ALTER TABLE "snippets" ADD COLUMN "hashed_password" TEXT;
@@ -0,0 +1,28 @@
+-- Put back the triggers from 0007, which index every
+-- snippet, before dropping the column they no longer
+-- mention. Encrypted snippets were never indexed, so
+-- the index is rebuilt to add them.
+DROP TRIGGER "snippets_fts_insert";
+DROP TRIGGER "snippets_fts_delete";
+DROP TRIGGER "snippets_fts_update";
+
+CREATE TRIGGER "snippets_fts_insert" AFTER INSERT ON "snippets" BEGIN
+	INSERT INTO "snippets_fts" ("rowid", "title", "content")
+	VALUES (new."id", new."title", new."content");
+END;
+
+CREATE TRIGGER "snippets_fts_delete" AFTER DELETE ON "snippets" BEGIN
+	INSERT INTO "snippets_fts" ("snippets_fts", "rowid", "title", "content")
+	VALUES ('delete', old."id", old."title", old."content");
+END;
+
+CREATE TRIGGER "snippets_fts_update" AFTER UPDATE OF "title", "content" ON "snippets" BEGIN
+	INSERT INTO "snippets_fts" ("snippets_fts", "rowid", "title", "content")
+	VALUES ('delete', old."id", old."title", old."content");
+	INSERT INTO "snippets_fts" ("rowid", "title", "content")
+	VALUES (new."id", new."title", new."content");
+END;
+
+ALTER TABLE "snippets" DROP COLUMN "encrypted";
+
+INSERT INTO "snippets_fts" ("snippets_fts") VALUES ('rebuild');
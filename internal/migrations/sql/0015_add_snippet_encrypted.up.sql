@@ -0,0 +1,33 @@
+-- Whether a snippet was encrypted in the browser before
+-- it was sent, in which case its content is an opaque
+-- blob the server can't read.
+ALTER TABLE "snippets" ADD COLUMN "encrypted" INTEGER NOT NULL DEFAULT 0;
+
+-- Encrypted content would only fill the full-text index
+-- with noise, and their titles shouldn't be found either,
+-- so the triggers keeping the index in sync now skip
+-- encrypted snippets. Whether a snippet is encrypted
+-- never changes once it has been created.
+DROP TRIGGER "snippets_fts_insert";
+DROP TRIGGER "snippets_fts_delete";
+DROP TRIGGER "snippets_fts_update";
+
+CREATE TRIGGER "snippets_fts_insert" AFTER INSERT ON "snippets"
+WHEN new."encrypted" = 0 BEGIN
+	INSERT INTO "snippets_fts" ("rowid", "title", "content")
+	VALUES (new."id", new."title", new."content");
+END;
+
+CREATE TRIGGER "snippets_fts_delete" AFTER DELETE ON "snippets"
+WHEN old."encrypted" = 0 BEGIN
+	INSERT INTO "snippets_fts" ("snippets_fts", "rowid", "title", "content")
+	VALUES ('delete', old."id", old."title", old."content");
+END;
+
+CREATE TRIGGER "snippets_fts_update" AFTER UPDATE OF "title", "content" ON "snippets"
+WHEN new."encrypted" = 0 BEGIN
+	INSERT INTO "snippets_fts" ("snippets_fts", "rowid", "title", "content")
+	VALUES ('delete', old."id", old."title", old."content");
+	INSERT INTO "snippets_fts" ("rowid", "title", "content")
+	VALUES (new."id", new."title", new."content");
+END;
@@ -16,3 +16,10 @@ var ErrInvalidCredentials = errors.New("models: invalid credentials")
 // already in use
 var ErrDuplicateEmail = errors.New("models: duplicate email")
 
+// ErrInvalidCursor generates a new error when a
+// pagination cursor can't be decoded
+var ErrInvalidCursor = errors.New("models: invalid cursor")
+
+// ErrInvalidSort generates a new error when snippets
+// are listed in an order that doesn't exist
+var ErrInvalidSort = errors.New("models: invalid sort order")
@@ -0,0 +1,224 @@
+package models
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SortOrders maps the name of each order snippets can be
+// listed in to the column they are sorted by and the
+// direction of the sort. Snippets with the same value
+// are ordered by ID in the same direction, so every
+// snippet has a fixed place in the list.
+var SortOrders = map[string]struct {
+	Column	string
+	Desc		bool
+}{
+	"created": {Column: "s.created", Desc: true},
+	"expires": {Column: "s.expires", Desc: false},
+	"title":   {Column: "s.title", Desc: false},
+}
+
+// DefaultSort is the order snippets are listed in when
+// no order is chosen, newest first.
+const DefaultSort = "created"
+
+// ListOptions defines a type to choose which page of
+// snippets List() returns.
+//
+// Options available:
+//	1. Sort - a key of SortOrders, or DefaultSort if empty
+//	2. After - cursor of the snippet the page starts after
+//	3. Before - cursor of the snippet the page ends before
+//	4. Limit - the number of snippets on the page
+//	5. Tag - only list snippets with this tag, if set
+//
+// When neither After nor Before are set, the first page
+// is returned.
+type ListOptions struct {
+	Sort		string
+	After		string
+	Before	string
+	Limit		int
+	Tag			string
+}
+
+// Page defines a type to hold one page of snippets,
+// along with the total number of snippets across every
+// page and the cursors to pass as ListOptions.After and
+// ListOptions.Before to get the next and previous pages.
+// Next and Prev are empty when there is no such page.
+// The struct tags name the fields when the API encodes
+// a page as JSON, alongside its snippets.
+type Page struct {
+	Snippets	[]*Snippet	`json:"-"`
+	Total			int					`json:"total"`
+	Next			string			`json:"next"`
+	Prev			string			`json:"prev"`
+}
+
+/*
+List function gets a page of unexpired public
+snippets which don't have a password and aren't
+encrypted, using keyset pagination.
+Rather than skipping over an offset of rows, each page
+starts from the sort value and ID of the last snippet
+on the page before, which the cursors hold. Pages stay
+fast however far through the list they are, and don't
+shift when snippets are added.
+*/
+func (m *SnippetModel) List(opts ListOptions) (*Page, error) {
+	if opts.Sort == "" {
+		opts.Sort = DefaultSort
+	}
+	order, ok := SortOrders[opts.Sort]
+	if !ok {
+		return nil, ErrInvalidSort
+	}
+
+	// Get the time right now to filter out
+	// expired snippets
+	now := time.Now()
+
+	// The snippets that can be listed at all, with the
+	// arguments for the placeholders
+	where := `s.expires > ? AND s.deleted IS NULL AND s.visibility = ?
+		AND s.hashed_password IS NULL AND s.encrypted = 0`
+	args := []any{timeToString(now), VisibilityPublic}
+
+	// Only list snippets with the chosen tag
+	if opts.Tag != "" {
+		where += ` AND s.id IN (
+			SELECT st.snippet_id FROM snippet_tags st
+			JOIN tags t ON t.id = st.tag_id
+			WHERE t.name = ?
+		)`
+		args = append(args, opts.Tag)
+	}
+
+	page := &Page{Snippets: []*Snippet{}}
+
+	// Count every snippet in the list, not just this page
+	stmt := `SELECT COUNT(*) ` + snippetFrom + ` WHERE ` + where
+	err := m.DB.QueryRow(stmt, args...).Scan(&page.Total)
+	if err != nil {
+		return nil, err
+	}
+
+	// Going backwards, the snippets before the cursor
+	// are fetched in reverse order and then flipped
+	// round, so the page ends right before the cursor.
+	cursor := opts.After
+	backwards := opts.Before != ""
+	if backwards {
+		cursor = opts.Before
+	}
+	desc := order.Desc != backwards
+
+	direction, compare := "ASC", ">"
+	if desc {
+		direction, compare = "DESC", "<"
+	}
+
+	// Start the page from the snippet in the cursor
+	if cursor != "" {
+		value, id, err := decodeCursor(cursor)
+		if err != nil {
+			return nil, err
+		}
+		where += fmt.Sprintf(` AND (%s, s.id) %s (?, ?)`, order.Column, compare)
+		args = append(args, value, id)
+	}
+
+	// SQL statement to execute. Ask for one more snippet
+	// than fits on the page, to find out if there are
+	// more snippets beyond it.
+	stmt = `SELECT ` + snippetColumns + snippetFrom + `
+					WHERE ` + where + fmt.Sprintf(`
+					ORDER BY %s %s, s.id %s LIMIT ?`, order.Column, direction, direction)
+
+	snippets, err := m.query(stmt, append(args, opts.Limit+1)...)
+	if err != nil {
+		return nil, err
+	}
+
+	more := len(snippets) > opts.Limit
+	if more {
+		snippets = snippets[:opts.Limit]
+	}
+
+	if backwards {
+		for i, j := 0, len(snippets)-1; i < j; i, j = i+1, j-1 {
+			snippets[i], snippets[j] = snippets[j], snippets[i]
+		}
+	}
+	page.Snippets = snippets
+
+	if len(snippets) == 0 {
+		return page, nil
+	}
+
+	// There is always a page back the way we came, and a
+	// page further on if there were more snippets.
+	first := encodeCursor(opts.Sort, snippets[0])
+	last := encodeCursor(opts.Sort, snippets[len(snippets)-1])
+
+	if backwards {
+		page.Next = last
+		if more {
+			page.Prev = first
+		}
+	} else {
+		if more {
+			page.Next = last
+		}
+		if opts.After != "" {
+			page.Prev = first
+		}
+	}
+
+	return page, nil
+}
+
+/*
+encodeCursor function returns a cursor holding the
+value a snippet is sorted by and its ID, encoded to be
+safe to use in a URL.
+*/
+func encodeCursor(sort string, s *Snippet) string {
+	var value string
+
+	switch sort {
+	case "expires":
+		value = timeToString(s.Expires)
+	case "title":
+		value = s.Title
+	default:
+		value = timeToString(s.Created)
+	}
+
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.Itoa(s.ID) + ":" + value))
+}
+
+/*
+decodeCursor function returns the sort value and ID
+held in a cursor. ErrInvalidCursor is returned if the
+cursor is malformed.
+*/
+func decodeCursor(cursor string) (string, int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return "", 0, ErrInvalidCursor
+	}
+
+	number, value, ok := strings.Cut(string(b), ":")
+	id, err := strconv.Atoi(number)
+	if !ok || err != nil {
+		return "", 0, ErrInvalidCursor
+	}
+
+	return value, id, nil
+}
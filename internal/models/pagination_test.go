@@ -0,0 +1,169 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCursor(t *testing.T) {
+	s := &Snippet{
+		ID:				42,
+		Title:		"Colons: in titles",
+		Created:	time.Date(2024, 1, 25, 17, 30, 0, 0, time.UTC),
+		Expires:	time.Date(2025, 1, 25, 17, 30, 0, 0, time.UTC),
+	}
+
+	tests := []struct {
+		sort	string
+		want	string
+	}{
+		{"created", "2024-01-25 17:30:00"},
+		{"expires", "2025-01-25 17:30:00"},
+		{"title", "Colons: in titles"},
+	}
+
+	// Each cursor decodes to the value the snippet is
+	// sorted by and its ID
+	for _, tt := range tests {
+		t.Run(tt.sort, func(t *testing.T) {
+			value, id, err := decodeCursor(encodeCursor(tt.sort, s))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if value != tt.want || id != s.ID {
+				t.Errorf("got %q and ID %d, want %q and ID %d", value, id, tt.want, s.ID)
+			}
+		})
+	}
+}
+
+func TestDecodeCursorInvalid(t *testing.T) {
+	tests := []struct {
+		name		string
+		cursor	string
+	}{
+		{"Not base64", "not a cursor!"},
+		{"No separator", "NDI"},
+		{"ID not a number", "Zm9vOmJhcg"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := decodeCursor(tt.cursor)
+			if !errors.Is(err, ErrInvalidCursor) {
+				t.Errorf("got error %v, want ErrInvalidCursor", err)
+			}
+		})
+	}
+}
+
+func TestList(t *testing.T) {
+	db := newTestDB(t)
+	m := &SnippetModel{DB: db}
+
+	// Add five snippets, all created in the same second,
+	// so they are ordered by ID alone
+	var titles []string
+	for i := 1; i <= 5; i++ {
+		title := fmt.Sprintf("Snippet %d", i)
+		newTestSnippet(t, db, testSnippet{title: title})
+		titles = append(titles, title)
+	}
+	_, err := db.Exec(`UPDATE snippets SET created = '2024-01-25 17:30:00'`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pageTitles := func(p *Page) []string {
+		titles := []string{}
+		for _, s := range p.Snippets {
+			titles = append(titles, s.Title)
+		}
+		return titles
+	}
+
+	// Walk forwards through every page, newest first
+	first, err := m.List(ListOptions{Limit: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := m.List(ListOptions{Limit: 2, After: first.Next})
+	if err != nil {
+		t.Fatal(err)
+	}
+	last, err := m.List(ListOptions{Limit: 2, After: second.Next})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name			string
+		page			*Page
+		want			[]string
+		hasPrev		bool
+		hasNext		bool
+	}{
+		{"First page", first, []string{"Snippet 5", "Snippet 4"}, false, true},
+		{"Second page", second, []string{"Snippet 3", "Snippet 2"}, true, true},
+		{"Last page", last, []string{"Snippet 1"}, true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := pageTitles(tt.page); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+			if tt.page.Total != len(titles) {
+				t.Errorf("got total %d, want %d", tt.page.Total, len(titles))
+			}
+			if hasPrev := tt.page.Prev != ""; hasPrev != tt.hasPrev {
+				t.Errorf("got prev %q, want a prev cursor %t", tt.page.Prev, tt.hasPrev)
+			}
+			if hasNext := tt.page.Next != ""; hasNext != tt.hasNext {
+				t.Errorf("got next %q, want a next cursor %t", tt.page.Next, tt.hasNext)
+			}
+		})
+	}
+
+	// Going back from the last page gives the second
+	// page again
+	t.Run("Back a page", func(t *testing.T) {
+		back, err := m.List(ListOptions{Limit: 2, Before: last.Prev})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := pageTitles(back); !reflect.DeepEqual(got, pageTitles(second)) {
+			t.Errorf("got %v, want %v", got, pageTitles(second))
+		}
+		if back.Next == "" || back.Prev == "" {
+			t.Errorf("got next %q and prev %q, want both", back.Next, back.Prev)
+		}
+	})
+
+	t.Run("Sorted by title", func(t *testing.T) {
+		page, err := m.List(ListOptions{Sort: "title", Limit: 3})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := pageTitles(page); !reflect.DeepEqual(got, titles[:3]) {
+			t.Errorf("got %v, want %v", got, titles[:3])
+		}
+	})
+
+	t.Run("Invalid cursor", func(t *testing.T) {
+		_, err := m.List(ListOptions{Limit: 2, After: "not a cursor!"})
+		if !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("got error %v, want ErrInvalidCursor", err)
+		}
+	})
+
+	t.Run("Invalid sort", func(t *testing.T) {
+		_, err := m.List(ListOptions{Sort: "colour", Limit: 2})
+		if !errors.Is(err, ErrInvalidSort) {
+			t.Errorf("got error %v, want ErrInvalidSort", err)
+		}
+	})
+}
@@ -0,0 +1,132 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// Revision defines a type to hold one saved version of
+// a snippet. Every time a snippet is created or edited
+// a new revision is stored, numbered from 1 upwards,
+// so the full history of the snippet is kept. The
+// fields correspond to the fields in the SQLite
+// snippet_revisions table, except UserName which is
+// joined in from the users table.
+type Revision struct {
+	SnippetID	int
+	Version		int
+	Title			string
+	Content		string
+	Created		time.Time
+	UserID		int
+	UserName	string
+}
+
+// revisionColumns lists the columns, in order, that
+// scanRevision() expects, selected from the
+// snippet_revisions table (aliased "r") left joined to
+// the users table (aliased "u").
+const revisionColumns = `
+	r.snippet_id, r.version, r.title, r.content, r.created,
+	COALESCE(r.user_id, 0), COALESCE(u.name, '')
+`
+
+// revisionFrom is the FROM clause matching revisionColumns.
+const revisionFrom = `
+	FROM snippet_revisions r LEFT JOIN users u ON u.id = r.user_id
+`
+
+/*
+scanRevision function scans a row selected with
+revisionColumns into a new Revision struct.
+*/
+func scanRevision(row scanner) (*Revision, error) {
+	r := &Revision{}
+
+	// Hold the record's created field for conversion to
+	// Go's time.Time format
+	var createdTime string
+
+	err := row.Scan(&r.SnippetID, &r.Version, &r.Title, &r.Content, &createdTime, &r.UserID, &r.UserName)
+	if err != nil {
+		return nil, err
+	}
+
+	r.Created = stringToTime(createdTime)
+
+	return r, nil
+}
+
+/*
+insertRevision function stores the next revision of
+the snippet with the ID snippetID, as part of the
+transaction tx.
+*/
+func insertRevision(tx *sql.Tx, snippetID int, title string, content string, created time.Time, userID int) error {
+	// SQL statement to execute. The version number is
+	// one more than the snippet's latest revision.
+	stmt := `
+		INSERT INTO snippet_revisions (snippet_id, version, title, content, created, user_id)
+		SELECT ?, COALESCE(MAX(version), 0) + 1, ?, ?, ?, ?
+		FROM snippet_revisions WHERE snippet_id = ?
+	`
+
+	_, err := tx.Exec(stmt, snippetID, title, content, timeToString(created), userID, snippetID)
+	return err
+}
+
+/*
+Revisions function gets every revision of the snippet
+with the ID snippetID, newest first.
+*/
+func (m *SnippetModel) Revisions(snippetID int) ([]*Revision, error) {
+	// SQL statement to execute
+	stmt := `SELECT ` + revisionColumns + revisionFrom + `
+					WHERE r.snippet_id = ?
+					ORDER BY r.version DESC`
+
+	rows, err := m.DB.Query(stmt, snippetID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	revisions := []*Revision{}
+
+	for rows.Next() {
+		r, err := scanRevision(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		revisions = append(revisions, r)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return revisions, nil
+}
+
+/*
+Revision function gets a single revision of the
+snippet with the ID snippetID by its version number.
+*/
+func (m *SnippetModel) Revision(snippetID int, version int) (*Revision, error) {
+	// SQL statement to execute
+	stmt := `SELECT ` + revisionColumns + revisionFrom + `
+					WHERE r.snippet_id = ? AND r.version = ?`
+
+	r, err := scanRevision(m.DB.QueryRow(stmt, snippetID, version))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNoRecord
+		} else {
+			return nil, err
+		}
+	}
+
+	return r, nil
+}
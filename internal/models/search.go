@@ -0,0 +1,119 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// HighlightStart and HighlightEnd surround the words
+// matching a search in SearchResult's Title and Excerpt.
+// They are control characters which won't appear in a
+// snippet, so the text can be HTML escaped before they
+// are swapped for real markup.
+const (
+	HighlightStart = "\x02"
+	HighlightEnd   = "\x03"
+)
+
+// SearchResult defines a type to hold a snippet
+// matching a search, along with its title and a short
+// excerpt of its content with the matching words marked
+// by HighlightStart and HighlightEnd.
+type SearchResult struct {
+	*Snippet
+	HighlightedTitle	string
+	Excerpt						string
+}
+
+/*
+Search function finds the unexpired public snippets
+without a password whose title or content match the
+words in query, best matches first. Matches in the
+title count for more than matches in the content.
+limit and offset choose which page of results is
+returned. Snippets with a password are left out, as
+the excerpts would give away their content, and so are
+encrypted snippets, which aren't in the full-text
+index.
+*/
+func (m *SnippetModel) Search(query string, limit int, offset int) ([]*SearchResult, error) {
+	// A query with no words matches nothing
+	match := ftsQuery(query)
+	if match == "" {
+		return []*SearchResult{}, nil
+	}
+
+	// Get the time right now to filter out
+	// expired snippets
+	now := time.Now()
+
+	// SQL statement to execute. bm25() scores how well
+	// each snippet matches, with lower scores being
+	// better, and weights the title ten times higher
+	// than the content.
+	stmt := `SELECT ` + snippetColumns + `,
+					highlight(snippets_fts, 0, ?, ?),
+					snippet(snippets_fts, 1, ?, ?, '…', 24)
+					FROM snippets_fts
+					JOIN snippets s ON s.id = snippets_fts.rowid
+					LEFT JOIN users u ON u.id = s.user_id
+					WHERE snippets_fts MATCH ?
+					AND s.expires > ? AND s.deleted IS NULL
+					AND s.visibility = ? AND s.hashed_password IS NULL
+					AND s.encrypted = 0
+					ORDER BY bm25(snippets_fts, 10.0, 1.0)
+					LIMIT ? OFFSET ?`
+
+	rows, err := m.DB.Query(stmt,
+		HighlightStart, HighlightEnd,
+		HighlightStart, HighlightEnd,
+		match, timeToString(now), VisibilityPublic, limit, offset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := []*SearchResult{}
+
+	for rows.Next() {
+		r := &SearchResult{}
+
+		r.Snippet, err = scanSnippet(rows, &r.HighlightedTitle, &r.Excerpt)
+		if err != nil {
+			return nil, err
+		}
+
+		results = append(results, r)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Load the tags of every matching snippet
+	snippets := make([]*Snippet, len(results))
+	for i, r := range results {
+		snippets[i] = r.Snippet
+	}
+
+	return results, loadTags(m.DB, snippets...)
+}
+
+/*
+ftsQuery function turns the words typed into the
+search box into an FTS5 query. Each word is quoted, so
+characters which mean something to FTS5, like quotes,
+brackets and "-", are searched for as plain text
+rather than causing syntax errors. A snippet must
+contain every word to match.
+*/
+func ftsQuery(query string) string {
+	words := strings.Fields(query)
+
+	for i, word := range words {
+		words[i] = `"` + strings.ReplaceAll(word, `"`, `""`) + `"`
+	}
+
+	return strings.Join(words, " ")
+}
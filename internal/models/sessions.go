@@ -0,0 +1,38 @@
+package models
+
+import "database/sql"
+
+// SessionModel is a type that wraps a database
+// connection pool. The sessions table itself belongs to
+// the scs session manager, this model only tidies up
+// after it.
+type SessionModel struct {
+	DB	*sql.DB
+}
+
+/*
+	DeleteExpired removes up to limit expired sessions
+	from the sessions table and returns the number of
+	sessions removed. The session store keeps expiry
+	times as Julian day numbers.
+*/
+func (m *SessionModel) DeleteExpired(limit int) (int, error) {
+	// Create the SQL statement to delete a batch of
+	// expired sessions
+	stmt := `
+		DELETE FROM sessions WHERE token IN (
+			SELECT token FROM sessions
+			WHERE julianday('now') > expiry LIMIT ?
+		)
+	`
+
+	// Execute the statement and return the number of
+	// sessions removed
+	result, err := m.DB.Exec(stmt, limit)
+	if err != nil {
+		return 0, err
+	}
+
+	rows, err := result.RowsAffected()
+	return int(rows), err
+}
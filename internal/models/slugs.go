@@ -0,0 +1,43 @@
+package models
+
+import (
+	"crypto/rand"
+	"math/big"
+)
+
+// The characters slugs are made from. The first
+// character of a slug is always a letter, so a slug can
+// never be mistaken for a snippet ID.
+const (
+	slugLetters = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+	slugChars   = "0123456789" + slugLetters
+)
+
+// slugLength is the number of characters in a slug,
+// which gives around 71 random bits, far too many to
+// guess.
+const slugLength = 12
+
+/*
+newSlug function returns a random base62 slug, which
+is used in place of the ID in a snippet's URLs, so
+snippets can't be found by counting up through IDs.
+*/
+func newSlug() (string, error) {
+	b := make([]byte, slugLength)
+
+	for i := range b {
+		chars := slugChars
+		if i == 0 {
+			chars = slugLetters
+		}
+
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(len(chars))))
+		if err != nil {
+			return "", err
+		}
+		b[i] = chars[n.Int64()]
+	}
+
+	return string(b), nil
+}
@@ -4,18 +4,81 @@ import (
 	"database/sql"
 	"errors"
 	"time"
+
+	"golang.org/x/crypto/bcrypt"
 )
 
 // Snippet defines a type to hold data for an
 // individual snippet. The fields of the struct
 // correspond to the fields in SQLite snippets
-// table.
+// table. UserName is not stored on the snippet, it
+// is joined in from the users table, and Tags are
+// loaded from the tags table. Deleted is the zero time
+// unless the snippet is in the trash. Language is the
+// key of the programming language the snippet is
+// highlighted as, "text" for plain text. Visibility is
+// one of the Visibility constants, and Slug is the
+// random name of the snippet in URLs. Legacy is true
+// for snippets created before slugs, which can still be
+// found by their ID so that old links keep working.
+// Burn is true for snippets that are deleted the first
+// time someone other than their owner reads them, and
+// Protected is true for snippets with a password. The
+// password's hash is only read by Unlock(). Encrypted is
+// true for snippets encrypted in the browser, whose
+// Content is an opaque blob the server can't read.
+//
+// The struct tags name the fields when a snippet is
+// encoded as JSON by the API. The owner's ID and the
+// time a snippet was deleted are left out.
 type Snippet struct {
-	ID				int
-	Title			string
-	Content		string
-	Created		time.Time
-	Expires		time.Time
+	ID				int				`json:"id"`
+	Title			string		`json:"title"`
+	Content		string		`json:"content"`
+	Created		time.Time	`json:"created"`
+	Expires		time.Time	`json:"expires"`
+	UserID		int				`json:"-"`
+	UserName	string		`json:"author"`
+	Deleted		time.Time	`json:"-"`
+	Tags			[]string	`json:"tags"`
+	Language	string		`json:"language"`
+	Visibility	string	`json:"visibility"`
+	Slug			string		`json:"slug"`
+	Legacy		bool			`json:"-"`
+	Burn			bool			`json:"burn_after_reading"`
+	Protected	bool			`json:"password_protected"`
+	Encrypted	bool			`json:"encrypted"`
+}
+
+// Never is the expiry date of snippets which never
+// expire. It is stored like any other date, so the
+// queries which skip expired snippets don't need to
+// treat these snippets differently.
+var Never = time.Date(9999, 12, 31, 0, 0, 0, 0, time.UTC)
+
+// NeverExpires function returns true if the snippet
+// doesn't expire.
+func (s *Snippet) NeverExpires() bool {
+	return !s.Expires.Before(Never)
+}
+
+// TrashRetention is how long a deleted snippet stays
+// in its owner's trash, where it can be restored,
+// before it is permanently purged.
+const TrashRetention = 30 * 24 * time.Hour
+
+// OwnedBy function returns true if the snippet was
+// created by the user with the ID userID. Snippets
+// created before ownership was recorded are not owned
+// by anyone.
+func (s *Snippet) OwnedBy(userID int) bool {
+	return s.UserID != 0 && s.UserID == userID
+}
+
+// PurgeAt function returns the time a deleted snippet
+// will be permanently purged from the trash.
+func (s *Snippet) PurgeAt() time.Time {
+	return s.Deleted.Add(TrashRetention)
 }
 
 // SnippetModel defines a type to wrap an
@@ -24,76 +87,549 @@ type SnippetModel struct {
 	DB	*sql.DB
 }
 
+// snippetColumns lists the columns, in order, that
+// scanSnippet() expects. Every query returning
+// snippets selects these columns from the snippets
+// table (aliased "s") left joined to the users table
+// (aliased "u"). Snippets created before ownership was
+// recorded have no user, and snippets that aren't in
+// the trash have no deleted time, so COALESCE() turns
+// the missing values into zero values.
+const snippetColumns = `
+	s.id, s.title, s.content, s.created, s.expires,
+	COALESCE(s.user_id, 0), COALESCE(u.name, ''),
+	COALESCE(s.deleted, ''), s.language,
+	s.visibility, s.slug, s.legacy, s.burn,
+	s.hashed_password IS NOT NULL, s.encrypted
+`
+
+// snippetFrom is the FROM clause matching snippetColumns.
+const snippetFrom = `
+	FROM snippets s LEFT JOIN users u ON u.id = s.user_id
+`
+
+// scanner is satisfied by both *sql.Row and *sql.Rows,
+// so one function can scan either.
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+// querier is satisfied by both *sql.DB and *sql.Tx, so
+// one function can query inside or outside a
+// transaction.
+type querier interface {
+	Query(query string, args ...any) (*sql.Rows, error)
+}
+
+/*
+scanSnippet function scans a row selected with
+snippetColumns into a new Snippet struct, converting
+the SQLite datetime strings to Go's time.Time format.
+Any columns selected after snippetColumns are scanned
+into extra.
+*/
+func scanSnippet(row scanner, extra ...any) (*Snippet, error) {
+	// Initialize a pointer to a new Snippet struct
+	s := &Snippet{}
+
+	// Hold the record's created, expires and deleted
+	// fields for conversion to Go's time.Time format
+	var createdTime, expiredTime, deletedTime string
+
+	// Scan the values of the row to the corresponding
+	// fields in the Snippet struct. The arguments are
+	// *pointers" to the copied data location.
+	dest := []any{&s.ID, &s.Title, &s.Content, &createdTime, &expiredTime, &s.UserID, &s.UserName, &deletedTime, &s.Language, &s.Visibility, &s.Slug, &s.Legacy, &s.Burn, &s.Protected, &s.Encrypted}
+	err := row.Scan(append(dest, extra...)...)
+	if err != nil {
+		return nil, err
+	}
+
+	// Convert the record's time strings to Go's
+	// time.Time format and add to snippet struct
+	s.Created = stringToTime(createdTime)
+	s.Expires = stringToTime(expiredTime)
+	s.Deleted = stringToTime(deletedTime)
+
+	return s, nil
+}
+
 /*
 Insert function inserts a new snippet into
-the database.
+the database, written in language, with the given
+visibility, owned by the user with the ID userID and
+tagged with tags. If burn is true the snippet is burnt
+after reading, if encrypted is true the content was
+encrypted in the browser, and if password isn't empty
+the snippet is protected by it. The snippet expires
+at the time expires, which is Never for snippets that
+don't expire. The snippet's first revision is stored
+along with it. The new snippet's random slug is
+returned, which is how it is found from then on.
 */
-func (m *SnippetModel) Insert(title string, content string, expires int) (int, error) {
-	
+func (m *SnippetModel) Insert(title string, content string, language string, visibility string, burn bool, encrypted bool, password string, tags []string, expires time.Time, userID int) (string, error) {
+
 	// Get the time right now for database record
 	// created field
 	now := time.Now()
 
-	// Create an expires date by adding the expires time
-	// to the current time
-	exp := now.AddDate(0, 0, expires)
-	
+	// Generate the slug the snippet is reached by
+	slug, err := newSlug()
+	if err != nil {
+		return "", err
+	}
+
+	// Create a bcrypt hash of the password, if there is
+	// one. Snippets without a password store NULL.
+	var hashedPassword any
+	if password != "" {
+		hash, err := bcrypt.GenerateFromPassword([]byte(password), 12)
+		if err != nil {
+			return "", err
+		}
+		hashedPassword = hash
+	}
+
+	// Begin a transaction, so the snippet and its first
+	// revision are stored together or not at all. The
+	// deferred Rollback() does nothing once the
+	// transaction has been committed.
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+
 	// SQL statement to execute.
 	stmt := `
-		INSERT INTO snippets (title, content, created, expires)
-		VALUES(?, ?, ?, ?)
+		INSERT INTO snippets (title, content, language, visibility, slug, burn, encrypted, hashed_password, created, expires, user_id)
+		VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 	// Execute the SQL statement
-	result, err := m.DB.Exec(stmt, title, content, now.Format(dbTimeFormat), exp.Format(dbTimeFormat))
+	result, err := tx.Exec(stmt, title, content, language, visibility, slug, burn, encrypted, hashedPassword, timeToString(now), timeToString(expires), userID)
 	if err != nil {
-		return 0, err
+		return "", err
 	}
 
 	// Use lastInsertId on the result to get the
 	// ID of new record
 	id, err := result.LastInsertId()
+	if err != nil {
+		return "", err
+	}
+
+	// Store the first revision of the snippet
+	err = insertRevision(tx, int(id), title, content, now, userID)
+	if err != nil {
+		return "", err
+	}
+
+	// Tag the snippet
+	err = setTags(tx, int(id), tags)
+	if err != nil {
+		return "", err
+	}
+
+	// Return the slug of the new snippet
+	return slug, tx.Commit()
+}
+
+/*
+Update function replaces the title, content, language,
+visibility and tags of an existing snippet, edited by
+the user with the ID userID. The previous title and
+content stay in the snippet's revision history. The
+snippet's expiry date is moved to expires, unless
+expires is the zero time, which leaves it as it is.
+*/
+func (m *SnippetModel) Update(id int, title string, content string, language string, visibility string, tags []string, expires time.Time, userID int) error {
+	// Begin a transaction, so the snippet and its new
+	// revision are changed together or not at all
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// The new expiry date, or NULL to keep the current one
+	var exp any
+	if !expires.IsZero() {
+		exp = timeToString(expires)
+	}
+
+	// SQL statement to execute
+	stmt := `
+		UPDATE snippets SET title = ?, content = ?, language = ?,
+		visibility = ?, expires = COALESCE(?, expires)
+		WHERE id = ?
+	`
+
+	// Execute the SQL statement
+	result, err := tx.Exec(stmt, title, content, language, visibility, exp, id)
+	if err != nil {
+		return err
+	}
+
+	// If no row was changed, the snippet does not exist
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrNoRecord
+	}
+
+	// Store the new revision of the snippet
+	err = insertRevision(tx, id, title, content, time.Now(), userID)
+	if err != nil {
+		return err
+	}
+
+	// Replace the snippet's tags
+	err = setTags(tx, id, tags)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+/*
+Delete function moves a snippet to its owner's
+trash. The snippet is hidden everywhere else, but can
+be restored until TrashRetention has passed.
+*/
+func (m *SnippetModel) Delete(id int) error {
+	// SQL statement to execute
+	stmt := `
+		UPDATE snippets SET deleted = ?
+		WHERE id = ? AND deleted IS NULL
+	`
+
+	// Execute the SQL statement
+	result, err := m.DB.Exec(stmt, timeToString(time.Now()), id)
+	if err != nil {
+		return err
+	}
+
+	// If no row was changed, the snippet does not exist
+	// or is already in the trash
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrNoRecord
+	}
+
+	return nil
+}
+
+/*
+Restore function takes the snippet with the given slug,
+owned by the user with the ID userID, back out of the
+trash. ErrNoRecord is returned if the snippet isn't in
+that user's trash.
+*/
+func (m *SnippetModel) Restore(slug string, userID int) error {
+	// Snippets deleted before the cutoff are waiting to
+	// be purged and can no longer be restored
+	cutoff := time.Now().Add(-TrashRetention)
+
+	// SQL statement to execute
+	stmt := `
+		UPDATE snippets SET deleted = NULL
+		WHERE slug = ? AND user_id = ? AND deleted > ?
+	`
+
+	// Execute the SQL statement
+	result, err := m.DB.Exec(stmt, slug, userID, timeToString(cutoff))
+	if err != nil {
+		return err
+	}
+
+	// If no row was changed, there was nothing to restore
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrNoRecord
+	}
+
+	return nil
+}
+
+/*
+Trash function gets the snippets owned by the user
+with the ID userID which are in the trash and can
+still be restored, most recently deleted first.
+*/
+func (m *SnippetModel) Trash(userID int) ([]*Snippet, error) {
+	// Snippets deleted before the cutoff are waiting
+	// to be purged
+	cutoff := time.Now().Add(-TrashRetention)
+
+	// SQL statement to execute
+	stmt := `SELECT ` + snippetColumns + snippetFrom + `
+					WHERE s.user_id = ? AND s.deleted > ?
+					ORDER BY s.deleted DESC`
+
+	return m.query(stmt, userID, timeToString(cutoff))
+}
+
+/*
+Purge function permanently removes every snippet that
+has been in the trash for longer than TrashRetention.
+It returns the number of snippets removed.
+*/
+func (m *SnippetModel) Purge() (int, error) {
+	// Snippets deleted before the cutoff are removed
+	cutoff := timeToString(time.Now().Add(-TrashRetention))
+
+	// Begin a transaction, so the snippets and their
+	// revisions are removed together
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	// Remove the revision history and tags of the
+	// snippets
+	for _, table := range []string{"snippet_revisions", "snippet_tags"} {
+		stmt := `
+			DELETE FROM ` + table + ` WHERE snippet_id IN (
+				SELECT id FROM snippets WHERE deleted <= ?
+			)
+		`
+		_, err = tx.Exec(stmt, cutoff)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	// Remove the snippets themselves
+	stmt := `DELETE FROM snippets WHERE deleted <= ?`
+
+	result, err := tx.Exec(stmt, cutoff)
+	if err != nil {
+		return 0, err
+	}
+
+	// Return the number of snippets removed
+	rows, err := result.RowsAffected()
 	if err != nil {
 		return 0, err
 	}
 
-	// Convert ID (int64) to int and return
-	return int(id), nil
+	return int(rows), tx.Commit()
 }
 
+/*
+DeleteExpired function permanently removes up to limit
+expired snippets, along with their revisions. It
+returns the number of snippets removed, so callers can
+keep calling it until fewer than limit are removed.
+*/
+func (m *SnippetModel) DeleteExpired(limit int) (int, error) {
+	// Get the time right now to find expired snippets
+	now := timeToString(time.Now())
+
+	// Begin a transaction, so the snippets and their
+	// revisions are removed together
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	// The batch of expired snippets to remove. Ordering
+	// by ID means both DELETE statements below pick out
+	// the same snippets.
+	batch := `
+		SELECT id FROM snippets WHERE expires <= ?
+		ORDER BY id LIMIT ?
+	`
+
+	// Remove the revision history and tags of the
+	// snippets
+	for _, table := range []string{"snippet_revisions", "snippet_tags"} {
+		stmt := `DELETE FROM ` + table + ` WHERE snippet_id IN (` + batch + `)`
+
+		_, err = tx.Exec(stmt, now, limit)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	// Remove the snippets themselves
+	stmt := `DELETE FROM snippets WHERE id IN (` + batch + `)`
+
+	result, err := tx.Exec(stmt, now, limit)
+	if err != nil {
+		return 0, err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(rows), tx.Commit()
+}
 
 /*
 Get function returns a specific snippet
-based on its id.
+based on its slug, if the user with the ID viewerID
+may view it: anyone with the slug can view a snippet,
+unless it is private and they aren't the owner.
+Snippets in the trash are not returned.
+*/
+func (m *SnippetModel) Get(slug string, viewerID int) (*Snippet, error) {
+	return m.get(`s.slug = ? AND (s.visibility <> ? OR s.user_id = ?)`, slug, VisibilityPrivate, viewerID)
+}
+
+/*
+GetLegacy function returns a legacy snippet based on
+its old sequential ID, so links from before slugs can
+be redirected. Only public snippets, and any snippet
+to its owner, are returned: redirecting the ID of an
+unlisted snippet would give away its slug.
+*/
+func (m *SnippetModel) GetLegacy(id int, viewerID int) (*Snippet, error) {
+	return m.get(`s.id = ? AND s.legacy = 1 AND (s.visibility = ? OR s.user_id = ?)`, id, VisibilityPublic, viewerID)
+}
+
+/*
+Unlock function checks password against the password
+of the snippet with the ID id. ErrInvalidCredentials is
+returned if it doesn't match, and ErrNoRecord if the
+snippet has no password.
+*/
+func (m *SnippetModel) Unlock(id int, password string) error {
+	var hashedPassword []byte
+
+	// SQL statement to execute
+	stmt := `
+		SELECT hashed_password FROM snippets
+		WHERE id = ? AND hashed_password IS NOT NULL
+	`
+
+	err := m.DB.QueryRow(stmt, id).Scan(&hashedPassword)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNoRecord
+		} else {
+			return err
+		}
+	}
+
+	// Check the hashed password against the password
+	// entered. If they don't match, return
+	// ErrInvalidCredentials error
+	err = bcrypt.CompareHashAndPassword(hashedPassword, []byte(password))
+	if err != nil {
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return ErrInvalidCredentials
+		} else {
+			return err
+		}
+	}
+
+	return nil
+}
+
+/*
+Burn function reads and deletes a burn after reading
+snippet based on its slug, if the user with the ID
+viewerID may view it, in the same way as Get. The
+snippet is read by the same statement that deletes it,
+so when two people ask for it at once only one of them
+gets it, and the other gets ErrNoRecord. The snippet's
+revisions and tags go with it, and it doesn't go to
+the trash.
+*/
+func (m *SnippetModel) Burn(slug string, viewerID int) (*Snippet, error) {
+	// Get the time right now to skip expired snippets
+	now := time.Now()
+
+	// Begin a transaction, so the snippet, its revisions
+	// and its tags are removed together
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// SQL statement to execute. RETURNING gives back the
+	// deleted row, with its columns in the order
+	// scanSnippet() expects. The owner's name can't be
+	// joined in, so it is looked up below.
+	stmt := `
+		DELETE FROM snippets
+		WHERE slug = ? AND burn = 1 AND expires > ? AND deleted IS NULL
+		AND (visibility <> ? OR user_id = ?)
+		RETURNING id, title, content, created, expires,
+		COALESCE(user_id, 0), '', COALESCE(deleted, ''), language,
+		visibility, slug, legacy, burn,
+		hashed_password IS NOT NULL, encrypted
+	`
+
+	s, err := scanSnippet(tx.QueryRow(stmt, slug, timeToString(now), VisibilityPrivate, viewerID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNoRecord
+		} else {
+			return nil, err
+		}
+	}
+
+	// Look up the owner's name, if there is an owner
+	stmt = `SELECT COALESCE((SELECT name FROM users WHERE id = ?), '')`
+
+	err = tx.QueryRow(stmt, s.UserID).Scan(&s.UserName)
+	if err != nil {
+		return nil, err
+	}
+
+	// Load the snippet's tags before they are removed
+	err = loadTags(tx, s)
+	if err != nil {
+		return nil, err
+	}
+
+	// Remove the revision history and tags of the
+	// snippet
+	for _, table := range []string{"snippet_revisions", "snippet_tags"} {
+		_, err = tx.Exec(`DELETE FROM `+table+` WHERE snippet_id = ?`, s.ID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return s, tx.Commit()
+}
+
+/*
+get function returns the unexpired snippet that
+matches the condition in where, which uses the
+placeholders in args.
 */
-func (m *SnippetModel) Get(id int) (*Snippet, error) {
-	
+func (m *SnippetModel) get(where string, args ...any) (*Snippet, error) {
+
 	// Get the time right now for database record
 	// created field
 	now := time.Now()
 
 	// SQL statement to get snippet
-	stmt:= `SELECT id, title, content, created, expires 
-					FROM snippets WHERE expires > ? AND id = ?`
-	
-	// Use the QueryRow() method to get row
-	row := m.DB.QueryRow(stmt, now.Format(dbTimeFormat), id)
+	stmt:= `SELECT ` + snippetColumns + snippetFrom + `
+					WHERE s.expires > ? AND s.deleted IS NULL AND ` + where
 
-	// Initialize a pointer to a new Snippet struct
-	s := &Snippet{}
+	// Use the QueryRow() method to get row and scan
+	// it into a new Snippet struct
+	s, err := scanSnippet(m.DB.QueryRow(stmt, append([]any{timeToString(now)}, args...)...))
 
-	// Scan the values of the row to the corresponding
-	// fields in the Snippet struct. The arguments are
-	// *pointers" to the copied data location. The
-	// variables "createdTime" and "expiredTime" are
-	// created at the top, to avoid duplicity.
-	err := row.Scan(&s.ID, &s.Title, &s.Content, &createdTime, &expiredTime)
-	
-	// Convert the record's time strings to Go's
-	// time.Time format and add to snippet struct
-	s.Created = stringToTime(createdTime)
-	s.Expires = stringToTime(expiredTime)
-
-	
 	// If the query returns no rows, row.Scan() returns
 	// a sql.ErrNoRows error. Check for error with the
 	// errors.Is function
@@ -104,28 +640,37 @@ func (m *SnippetModel) Get(id int) (*Snippet, error) {
 			return nil, err
 		}
 	}
-	// Return the Snippet
-	return s, nil
+	// Load the snippet's tags and return the Snippet
+	return s, loadTags(m.DB, s)
 }
 
 /*
-Latest function gets the latest 10 unexpired
-snippets.
+ByUser function gets all unexpired snippets owned
+by the user with the ID userID, newest first.
 */
-func (m *SnippetModel) Latest() ([]*Snippet, error) {
-	
-	// Get the time right now for database record
-	// created field
+func (m *SnippetModel) ByUser(userID int) ([]*Snippet, error) {
+
+	// Get the time right now to filter out
+	// expired snippets
 	now := time.Now()
 
 	// SQL statement to execute
-	stmt := `SELECT id, title, content, created, expires
-					FROM snippets WHERE expires > ?
-					ORDER BY id DESC LIMIT 10`
+	stmt := `SELECT ` + snippetColumns + snippetFrom + `
+					WHERE s.expires > ? AND s.user_id = ? AND s.deleted IS NULL
+					ORDER BY s.id DESC`
 
+	return m.query(stmt, timeToString(now), userID)
+}
+
+/*
+query function runs a statement selecting
+snippetColumns and returns the resulting snippets,
+with their tags loaded.
+*/
+func (m *SnippetModel) query(stmt string, args ...any) ([]*Snippet, error) {
 	// Use the Query() method, which returns a sql.Rows
 	// result
-	rows, err := m.DB.Query(stmt, now.Format(dbTimeFormat))
+	rows, err := m.DB.Query(stmt, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -139,22 +684,7 @@ func (m *SnippetModel) Latest() ([]*Snippet, error) {
 	// Use rows.Next() to iterate through the results,
 	// which prepares each row for the rows.Scan() method.
 	for rows.Next() {
-
-		// Create a pointer to a new zeroed Snippet struct
-		s := &Snippet{}
-
-		// Scan the values of the row to the corresponding
-		// fields in the Snippet struct. The arguments are
-		// *pointers" to the copied data location. The
-		// variables "createdTime" and "expiredTime" are
-		// created at the top, to avoid duplicity.
-		err := rows.Scan(&s.ID, &s.Title, &s.Content, &createdTime, &expiredTime)
-		
-		// Convert the record's time strings to Go's
-		// time.Time format and add to snippet struct
-		s.Created = stringToTime(createdTime)
-		s.Expires = stringToTime(expiredTime)
-
+		s, err := scanSnippet(rows)
 		if err != nil {
 			return nil, err
 		}
@@ -168,6 +698,7 @@ func (m *SnippetModel) Latest() ([]*Snippet, error) {
 		return nil, err
 	}
 
-	// Return Snippets slice
-	return snippets, nil
-}
\ No newline at end of file
+	// Load the tags of every snippet and return
+	// Snippets slice
+	return snippets, loadTags(m.DB, snippets...)
+}
@@ -0,0 +1,158 @@
+package models
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestBurn(t *testing.T) {
+	db := newTestDB(t)
+	m := &SnippetModel{DB: db}
+
+	owner := newTestUser(t, db, "owner")
+	other := newTestUser(t, db, "other")
+
+	t.Run("Only once", func(t *testing.T) {
+		slug := newTestSnippet(t, db, testSnippet{burn: true, tags: []string{"go"}, userID: owner})
+
+		s, err := m.Burn(slug, other)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s.Content != "Its content" || s.UserName != "owner" || len(s.Tags) != 1 {
+			t.Errorf("got content %q, author %q and tags %v, want the whole snippet", s.Content, s.UserName, s.Tags)
+		}
+
+		// The snippet is gone for everyone, its owner too
+		_, err = m.Burn(slug, other)
+		if !errors.Is(err, ErrNoRecord) {
+			t.Errorf("got error %v burning again, want ErrNoRecord", err)
+		}
+		_, err = m.Get(slug, owner)
+		if !errors.Is(err, ErrNoRecord) {
+			t.Errorf("got error %v getting it, want ErrNoRecord", err)
+		}
+	})
+
+	t.Run("Owner's view", func(t *testing.T) {
+		slug := newTestSnippet(t, db, testSnippet{burn: true, userID: owner})
+
+		// The owner views snippets with Get, which leaves
+		// the snippet to be burned by someone else
+		for i := 0; i < 2; i++ {
+			_, err := m.Get(slug, owner)
+			if err != nil {
+				t.Fatalf("got error %v on view %d, want the snippet", err, i+1)
+			}
+		}
+
+		_, err := m.Burn(slug, other)
+		if err != nil {
+			t.Errorf("got error %v burning after the owner's views, want the snippet", err)
+		}
+	})
+
+	t.Run("Not burn after reading", func(t *testing.T) {
+		slug := newTestSnippet(t, db, testSnippet{userID: owner})
+
+		_, err := m.Burn(slug, other)
+		if !errors.Is(err, ErrNoRecord) {
+			t.Errorf("got error %v, want ErrNoRecord", err)
+		}
+		_, err = m.Get(slug, other)
+		if err != nil {
+			t.Errorf("got error %v, want the snippet kept", err)
+		}
+	})
+
+	t.Run("Private", func(t *testing.T) {
+		slug := newTestSnippet(t, db, testSnippet{burn: true, visibility: VisibilityPrivate, userID: owner})
+
+		_, err := m.Burn(slug, other)
+		if !errors.Is(err, ErrNoRecord) {
+			t.Errorf("got error %v, want ErrNoRecord", err)
+		}
+		_, err = m.Get(slug, owner)
+		if err != nil {
+			t.Errorf("got error %v, want the snippet kept", err)
+		}
+	})
+}
+
+func TestUnlock(t *testing.T) {
+	db := newTestDB(t)
+	m := &SnippetModel{DB: db}
+
+	owner := newTestUser(t, db, "owner")
+	locked := newTestSnippet(t, db, testSnippet{password: "pa55word", userID: owner})
+	open := newTestSnippet(t, db, testSnippet{userID: owner})
+
+	tests := []struct {
+		name			string
+		slug			string
+		password	string
+		wantErr		error
+	}{
+		{"Correct password", locked, "pa55word", nil},
+		{"Wrong password", locked, "wrong", ErrInvalidCredentials},
+		{"Empty password", locked, "", ErrInvalidCredentials},
+		{"No password", open, "pa55word", ErrNoRecord},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := m.Get(tt.slug, owner)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = m.Unlock(s.ID, tt.password)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("got error %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestExpiresTimeZone(t *testing.T) {
+	db := newTestDB(t)
+	m := &SnippetModel{DB: db}
+
+	owner := newTestUser(t, db, "owner")
+
+	// Expiry times can come in any time zone, and have
+	// to be compared with the times in the database as
+	// the same instant
+	tests := []struct {
+		name		string
+		expires	time.Time
+		found		bool
+	}{
+		{"Behind UTC, in an hour", time.Now().In(time.FixedZone("UTC-10", -10*60*60)).Add(time.Hour), true},
+		{"Ahead of UTC, an hour ago", time.Now().In(time.FixedZone("UTC+10", 10*60*60)).Add(-time.Hour), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			slug, err := m.Insert("A snippet", "Its content", "text", VisibilityPublic, false, false, "", nil, tt.expires, owner)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			s, err := m.Get(slug, owner)
+			if !tt.found {
+				if !errors.Is(err, ErrNoRecord) {
+					t.Errorf("got %v and error %v, want ErrNoRecord", s, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !s.Expires.Equal(tt.expires.Truncate(time.Second)) {
+				t.Errorf("got expires %v, want %v", s.Expires, tt.expires)
+			}
+		})
+	}
+}
@@ -0,0 +1,141 @@
+package models
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+)
+
+// Tag defines a type to hold a tag along with the
+// number of unexpired snippets it is on.
+type Tag struct {
+	Name	string
+	Count	int
+}
+
+/*
+setTags function replaces the tags on the snippet with
+the ID snippetID with tags, as part of the transaction
+tx. Tags which don't exist yet are created.
+*/
+func setTags(tx *sql.Tx, snippetID int, tags []string) error {
+	// Remove the snippet's current tags
+	_, err := tx.Exec(`DELETE FROM snippet_tags WHERE snippet_id = ?`, snippetID)
+	if err != nil {
+		return err
+	}
+
+	for _, tag := range tags {
+		// Create the tag, unless it already exists
+		_, err = tx.Exec(`INSERT OR IGNORE INTO tags (name) VALUES (?)`, tag)
+		if err != nil {
+			return err
+		}
+
+		// Link the tag to the snippet
+		stmt := `
+			INSERT OR IGNORE INTO snippet_tags (snippet_id, tag_id)
+			SELECT ?, id FROM tags WHERE name = ?
+		`
+		_, err = tx.Exec(stmt, snippetID, tag)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+/*
+loadTags function fills in the Tags field of each of
+the snippets, in alphabetical order, with one query
+run on q.
+*/
+func loadTags(q querier, snippets ...*Snippet) error {
+	if len(snippets) == 0 {
+		return nil
+	}
+
+	// Look up each snippet by its ID, with a placeholder
+	// for every ID
+	byID := make(map[int]*Snippet, len(snippets))
+	args := make([]any, len(snippets))
+	for i, s := range snippets {
+		s.Tags = []string{}
+		byID[s.ID] = s
+		args[i] = s.ID
+	}
+
+	// SQL statement to execute
+	stmt := `SELECT st.snippet_id, t.name
+					FROM snippet_tags st JOIN tags t ON t.id = st.tag_id
+					WHERE st.snippet_id IN (?` + strings.Repeat(", ?", len(snippets)-1) + `)
+					ORDER BY t.name`
+
+	rows, err := q.Query(stmt, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int
+		var name string
+
+		err = rows.Scan(&id, &name)
+		if err != nil {
+			return err
+		}
+
+		byID[id].Tags = append(byID[id].Tags, name)
+	}
+
+	return rows.Err()
+}
+
+/*
+Tags function gets every tag which is on at least one
+unexpired public snippet which doesn't have a password
+and isn't encrypted, in alphabetical order, along with
+the number of snippets it is on.
+*/
+func (m *SnippetModel) Tags() ([]*Tag, error) {
+	// Get the time right now to filter out
+	// expired snippets
+	now := time.Now()
+
+	// SQL statement to execute
+	stmt := `SELECT t.name, COUNT(*)
+					FROM tags t
+					JOIN snippet_tags st ON st.tag_id = t.id
+					JOIN snippets s ON s.id = st.snippet_id
+					WHERE s.expires > ? AND s.deleted IS NULL AND s.visibility = ?
+					AND s.hashed_password IS NULL AND s.encrypted = 0
+					GROUP BY t.id
+					ORDER BY t.name`
+
+	rows, err := m.DB.Query(stmt, timeToString(now), VisibilityPublic)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := []*Tag{}
+
+	for rows.Next() {
+		t := &Tag{}
+
+		err = rows.Scan(&t.Name, &t.Count)
+		if err != nil {
+			return nil, err
+		}
+
+		tags = append(tags, t)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return tags, nil
+}
@@ -0,0 +1,91 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/robwestbrook/snippetbox/internal/migrations"
+)
+
+// newTestDB opens a new database in a temporary
+// directory with every migration applied. It needs
+// SQLite's FTS5 extension, so the tests using it are
+// skipped unless run with -tags sqlite_fts5.
+func newTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "test.db")+"?_busy_timeout=5000")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		db.Close()
+	})
+
+	_, err = migrations.Up(db)
+	if errors.Is(err, migrations.ErrNoFTS5) {
+		t.Skip("SQLite was built without FTS5; run the tests with make test or -tags sqlite_fts5")
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return db
+}
+
+// newTestUser adds a user named name to the database
+// and returns their ID.
+func newTestUser(t *testing.T, db *sql.DB, name string) int {
+	t.Helper()
+
+	users := &UserModel{DB: db}
+	err := users.Insert(name, name+"@example.com", "pa55word")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var id int
+	err = db.QueryRow(`SELECT id FROM users WHERE email = ?`, name+"@example.com").Scan(&id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return id
+}
+
+// testSnippet defines a type to hold the options of a
+// snippet added by newTestSnippet. The zero value is a
+// public snippet which expires in a day.
+type testSnippet struct {
+	title				string
+	visibility	string
+	burn				bool
+	encrypted		bool
+	password		string
+	tags				[]string
+	userID			int
+}
+
+// newTestSnippet adds a snippet to the database and
+// returns its slug.
+func newTestSnippet(t *testing.T, db *sql.DB, s testSnippet) string {
+	t.Helper()
+
+	if s.title == "" {
+		s.title = "A snippet"
+	}
+	if s.visibility == "" {
+		s.visibility = VisibilityPublic
+	}
+
+	snippets := &SnippetModel{DB: db}
+	slug, err := snippets.Insert(s.title, "Its content", "text", s.visibility, s.burn, s.encrypted, s.password, s.tags, time.Now().Add(24*time.Hour), s.userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return slug
+}
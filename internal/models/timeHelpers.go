@@ -3,21 +3,28 @@ package models
 import "time"
 
 // dbTimeFormat defines the format used to convert
-// date and time to a SQLite-friendly datetime.
+// date and time to a SQLite-friendly datetime. Every
+// datetime in the database is in UTC, which the format
+// doesn't record.
 const dbTimeFormat = "2006-01-02 15:04:05"
 
-// Create string variables to hold the record's
-// created and expires fields for conversion to
-// Go's time.Time format
-var createdTime string
-var expiredTime string
-
 /*
 stringToTime function takes in a string defining the
 time format and a time string from SQLite. It returns
-a GO time.Time format.
+a GO time.Time format, in UTC.
 */
 func stringToTime(stringToConvert string) (time.Time) {
 	res, _ := time.Parse(dbTimeFormat, stringToConvert)
 	return res
+}
+
+/*
+timeToString function converts a Go time.Time to the
+SQLite datetime string it is stored and compared as,
+after converting it to UTC. Times from anywhere else,
+whatever their time zone, can then be compared with
+the times in the database.
+*/
+func timeToString(t time.Time) string {
+	return t.UTC().Format(dbTimeFormat)
 }
\ No newline at end of file
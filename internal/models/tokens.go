@@ -0,0 +1,188 @@
+package models
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"time"
+)
+
+// The scopes an API token can have.
+//  1. ScopeRead - the token can only read snippets
+//  2. ScopeWrite - the token can also create, change
+//     and delete its user's snippets
+const (
+	ScopeRead  = "read"
+	ScopeWrite = "write"
+)
+
+// tokenPrefix starts every API token, so tokens are
+// easy to recognise, for example by secret scanners.
+const tokenPrefix = "sbx_"
+
+// Token defines a type to hold a personal API token.
+// The token itself is never stored, only its hash, so
+// it can't be shown again after it is created.
+// LastUsed is the zero time if the token has never
+// been used.
+type Token struct {
+	ID				int
+	UserID		int
+	Name			string
+	Scope			string
+	Created		time.Time
+	LastUsed	time.Time
+}
+
+// Allows function returns true if the token has the
+// given scope. Write tokens can also read.
+func (t *Token) Allows(scope string) bool {
+	return t.Scope == scope || t.Scope == ScopeWrite
+}
+
+/*
+hashToken function returns the SHA-256 hash of a
+token, which is what is stored in the database. The
+tokens are long and random, so unlike passwords they
+don't need a slow hash like bcrypt.
+*/
+func hashToken(token string) []byte {
+	hash := sha256.Sum256([]byte(token))
+	return hash[:]
+}
+
+/*
+InsertToken function creates a new API token for the
+user with the ID userID, and returns the token. This is
+the only time the token is available.
+*/
+func (m *UserModel) InsertToken(userID int, name string, scope string) (string, error) {
+	// Generate 32 random bytes for the token
+	b := make([]byte, 32)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+	token := tokenPrefix + base64.RawURLEncoding.EncodeToString(b)
+
+	// SQL statement to execute
+	stmt := `
+		INSERT INTO api_tokens (user_id, name, hash, scope, created)
+		VALUES (?, ?, ?, ?, ?)
+	`
+
+	_, err = m.DB.Exec(stmt, userID, name, hashToken(token), scope, timeToString(time.Now()))
+	if err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+/*
+Tokens function gets every API token belonging to the
+user with the ID userID, newest first.
+*/
+func (m *UserModel) Tokens(userID int) ([]*Token, error) {
+	// SQL statement to execute
+	stmt := `
+		SELECT id, user_id, name, scope, created, COALESCE(last_used, '')
+		FROM api_tokens WHERE user_id = ?
+		ORDER BY id DESC
+	`
+
+	rows, err := m.DB.Query(stmt, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tokens := []*Token{}
+
+	for rows.Next() {
+		t := &Token{}
+		var created, lastUsed string
+
+		err = rows.Scan(&t.ID, &t.UserID, &t.Name, &t.Scope, &created, &lastUsed)
+		if err != nil {
+			return nil, err
+		}
+
+		t.Created = stringToTime(created)
+		t.LastUsed = stringToTime(lastUsed)
+
+		tokens = append(tokens, t)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return tokens, nil
+}
+
+/*
+RevokeToken function deletes the API token with the ID
+id, if it belongs to the user with the ID userID.
+ErrNoRecord is returned if it doesn't.
+*/
+func (m *UserModel) RevokeToken(id int, userID int) error {
+	// SQL statement to execute
+	stmt := `DELETE FROM api_tokens WHERE id = ? AND user_id = ?`
+
+	result, err := m.DB.Exec(stmt, id, userID)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrNoRecord
+	}
+
+	return nil
+}
+
+/*
+AuthenticateToken function finds the API token
+matching token, belonging to a user who still exists,
+and records that it has been used.
+ErrInvalidCredentials is returned if there is no such
+token.
+*/
+func (m *UserModel) AuthenticateToken(token string) (*Token, error) {
+	// SQL statement to execute
+	stmt := `
+		SELECT t.id, t.user_id, t.name, t.scope, t.created
+		FROM api_tokens t JOIN users u ON u.id = t.user_id
+		WHERE t.hash = ?
+	`
+
+	t := &Token{}
+	var created string
+
+	err := m.DB.QueryRow(stmt, hashToken(token)).Scan(&t.ID, &t.UserID, &t.Name, &t.Scope, &created)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrInvalidCredentials
+		} else {
+			return nil, err
+		}
+	}
+
+	t.Created = stringToTime(created)
+	t.LastUsed = time.Now()
+
+	// Record when the token was last used
+	_, err = m.DB.Exec(`UPDATE api_tokens SET last_used = ? WHERE id = ?`, timeToString(t.LastUsed), t.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
@@ -0,0 +1,90 @@
+package models
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestInsertToken(t *testing.T) {
+	db := newTestDB(t)
+	m := &UserModel{DB: db}
+
+	owner := newTestUser(t, db, "owner")
+
+	token, err := m.InsertToken(owner, "Laptop", ScopeRead)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(token, "sbx_") {
+		t.Errorf("got token %q, want the prefix sbx_", token)
+	}
+
+	// Only the token's SHA-256 hash is stored
+	var hash []byte
+	err = db.QueryRow(`SELECT hash FROM api_tokens WHERE user_id = ?`, owner).Scan(&hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := sha256.Sum256([]byte(token))
+	if !bytes.Equal(hash, want[:]) {
+		t.Errorf("got hash %x, want %x", hash, want)
+	}
+}
+
+func TestAuthenticateToken(t *testing.T) {
+	db := newTestDB(t)
+	m := &UserModel{DB: db}
+
+	owner := newTestUser(t, db, "owner")
+
+	valid, err := m.InsertToken(owner, "Valid", ScopeWrite)
+	if err != nil {
+		t.Fatal(err)
+	}
+	revoked, err := m.InsertToken(owner, "Revoked", ScopeWrite)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tokens, err := m.Tokens(owner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Tokens are listed newest first
+	err = m.RevokeToken(tokens[0].ID, owner)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name		string
+		token		string
+		wantErr	error
+	}{
+		{"Valid token", valid, nil},
+		{"Revoked token", revoked, ErrInvalidCredentials},
+		{"Unknown token", "sbx_unknown", ErrInvalidCredentials},
+		{"Token's hash", string(hashToken(valid)), ErrInvalidCredentials},
+		{"Empty token", "", ErrInvalidCredentials},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tok, err := m.AuthenticateToken(tt.token)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got error %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			if tok.UserID != owner || tok.Scope != ScopeWrite {
+				t.Errorf("got user %d and scope %q, want user %d and scope %q", tok.UserID, tok.Scope, owner, ScopeWrite)
+			}
+			if tok.LastUsed.IsZero() {
+				t.Error("got no last used time, want one")
+			}
+		})
+	}
+}
@@ -9,13 +9,15 @@ import (
 	"golang.org/x/crypto/bcrypt"
 )
 
-// User defines a User type.
+// User defines a User type. The struct tags name the
+// fields when a user is encoded as JSON by the API. The
+// hashed password is never included.
 type User struct {
-	ID							int
-	Name 						string
-	Email 					string
-	HashedPassword	[]byte
-	Created 				time.Time
+	ID							int				`json:"id"`
+	Name 						string		`json:"name"`
+	Email 					string		`json:"email"`
+	HashedPassword	[]byte		`json:"-"`
+	Created 				time.Time	`json:"created"`
 }
 
 // UserModel is a type that wraps a database connection
@@ -48,7 +50,7 @@ func (m *UserModel) Insert(name, email, password string) error {
 
 	// Use the Exec() method to insert user data and
 	// hashed password into users table
-	_, err = m.DB.Exec(stmt, name, email, string(hashedPassword), now.Format(dbTimeFormat))
+	_, err = m.DB.Exec(stmt, name, email, string(hashedPassword), timeToString(now))
 
 	// If there is an error, process the error.
 	// If the error string contains "UNIQUE" and "users.email"
@@ -130,4 +132,32 @@ func (m *UserModel) Exists(id int) (bool, error) {
 
 	// Return exists and err
 	return exists, err
-}
\ No newline at end of file
+}
+
+/*
+	Get returns the user with a specific ID.
+	ErrNoRecord is returned if there is no such user.
+*/
+func (m *UserModel) Get(id int) (*User, error) {
+	u := &User{}
+	var created string
+
+	// Create SQL statement to retrieve user
+	stmt := `
+		SELECT id, name, email, created FROM users
+		WHERE id = ?
+	`
+
+	err := m.DB.QueryRow(stmt, id).Scan(&u.ID, &u.Name, &u.Email, &created)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNoRecord
+		} else {
+			return nil, err
+		}
+	}
+
+	u.Created = stringToTime(created)
+
+	return u, nil
+}
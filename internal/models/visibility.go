@@ -0,0 +1,23 @@
+package models
+
+// The visibility levels a snippet can have.
+//  1. VisibilityPublic - listed on the home page, in
+//     searches and on tag pages, and anyone can view it
+//  2. VisibilityUnlisted - never listed, so only
+//     people given the snippet's URL can find it
+//  3. VisibilityPrivate - only the owner can view it
+const (
+	VisibilityPublic   = "public"
+	VisibilityUnlisted = "unlisted"
+	VisibilityPrivate  = "private"
+)
+
+// Visibilities lists every visibility level, in the
+// order they are offered on the snippet forms.
+var Visibilities = []string{VisibilityPublic, VisibilityUnlisted, VisibilityPrivate}
+
+// IsPublic function returns true if anyone can find the
+// snippet.
+func (s *Snippet) IsPublic() bool {
+	return s.Visibility == VisibilityPublic
+}
@@ -0,0 +1,121 @@
+package models
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestGetVisibility(t *testing.T) {
+	db := newTestDB(t)
+	m := &SnippetModel{DB: db}
+
+	owner := newTestUser(t, db, "owner")
+	other := newTestUser(t, db, "other")
+
+	public := newTestSnippet(t, db, testSnippet{visibility: VisibilityPublic, userID: owner})
+	unlisted := newTestSnippet(t, db, testSnippet{visibility: VisibilityUnlisted, userID: owner})
+	private := newTestSnippet(t, db, testSnippet{visibility: VisibilityPrivate, userID: owner})
+
+	// A viewer ID of 0 is someone who isn't logged in
+	tests := []struct {
+		name			string
+		slug			string
+		viewerID	int
+		found			bool
+	}{
+		{"Public to anyone", public, 0, true},
+		{"Unlisted to anyone", unlisted, 0, true},
+		{"Unlisted to another user", unlisted, other, true},
+		{"Private to its owner", private, owner, true},
+		{"Private to another user", private, other, false},
+		{"Private to anyone", private, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := m.Get(tt.slug, tt.viewerID)
+
+			if !tt.found {
+				if !errors.Is(err, ErrNoRecord) {
+					t.Errorf("got %v and error %v, want ErrNoRecord", s, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if s.Slug != tt.slug {
+				t.Errorf("got slug %q, want %q", s.Slug, tt.slug)
+			}
+		})
+	}
+}
+
+func TestListedSnippets(t *testing.T) {
+	db := newTestDB(t)
+	m := &SnippetModel{DB: db}
+
+	owner := newTestUser(t, db, "owner")
+
+	// Only the public snippet may be listed, searched
+	// for or counted in the tag cloud. Every snippet has
+	// the word "gopher" in its title and a tag.
+	tests := []struct {
+		snippet	testSnippet
+		listed	bool
+	}{
+		{testSnippet{title: "Public gopher", tags: []string{"public"}}, true},
+		{testSnippet{title: "Unlisted gopher", visibility: VisibilityUnlisted, tags: []string{"unlisted"}}, false},
+		{testSnippet{title: "Private gopher", visibility: VisibilityPrivate, tags: []string{"private"}}, false},
+		{testSnippet{title: "Password gopher", password: "pa55word", tags: []string{"password"}}, false},
+		{testSnippet{title: "Encrypted gopher", encrypted: true, tags: []string{"encrypted"}}, false},
+	}
+
+	for _, tt := range tests {
+		tt.snippet.userID = owner
+		newTestSnippet(t, db, tt.snippet)
+	}
+
+	page, err := m.List(ListOptions{Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	results, err := m.Search("gopher", 10, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tags, err := m.Tags()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.snippet.title, func(t *testing.T) {
+			inList := false
+			for _, s := range page.Snippets {
+				inList = inList || s.Title == tt.snippet.title
+			}
+			inSearch := false
+			for _, r := range results {
+				inSearch = inSearch || r.Title == tt.snippet.title
+			}
+			inTags := false
+			for _, tag := range tags {
+				inTags = inTags || tag.Name == tt.snippet.tags[0]
+			}
+
+			if inList != tt.listed || inSearch != tt.listed || inTags != tt.listed {
+				t.Errorf("got listed %t, searchable %t and tagged %t, want %t", inList, inSearch, inTags, tt.listed)
+			}
+		})
+	}
+
+	// Listing by tag follows the same rules
+	page, err = m.List(ListOptions{Limit: 10, Tag: "unlisted"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Snippets) != 0 || page.Total != 0 {
+		t.Errorf("got %d snippets tagged unlisted, want none", page.Total)
+	}
+}
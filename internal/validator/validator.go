@@ -26,6 +26,12 @@ type Validator struct {
 // in the variable EmailRX.
 var EmailRX = regexp.MustCompile("^[a-zA-Z0-9.!#$%&'*+\\/=?^_`{|}~-]+@[a-zAZ0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?(?:\\.[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?)*$")
 
+// TagRX matches a tag: a lowercase letter or number,
+// followed by up to 29 more lowercase letters, numbers
+// or the characters "+#._-", so tags like "c++" and
+// "c#" are allowed.
+var TagRX = regexp.MustCompile(`^[a-z0-9][a-z0-9+#._-]{0,29}$`)
+
 
 // Valid function returns true if FieldErrors map
 // and NonFieldErrors slice don't contain any entries.
@@ -84,6 +90,17 @@ func PermittedInt(value int, permittedValues ...int) bool {
 	return false
 }
 
+// PermittedValue function returns true if a value is
+// in a list of permitted values of any comparable type.
+func PermittedValue[T comparable](value T, permittedValues ...T) bool {
+	for i := range permittedValues {
+		if value == permittedValues[i] {
+			return true
+		}
+	}
+	return false
+}
+
 // MinChars function returns true if a value contains
 // at least n characters.
 func MinChars(value string, n int) bool {
@@ -94,4 +111,22 @@ func MinChars(value string, n int) bool {
 // provided compiled regular expression pattern.
 func Matches(value string, rx *regexp.Regexp) bool {
 	return rx.MatchString(value)
-}
\ No newline at end of file
+}
+
+// AllMatch function returns true if every value in a
+// slice matches a provided compiled regular expression
+// pattern.
+func AllMatch(values []string, rx *regexp.Regexp) bool {
+	for _, value := range values {
+		if !rx.MatchString(value) {
+			return false
+		}
+	}
+	return true
+}
+
+// MaxItems function returns true if a slice contains
+// no more than n values.
+func MaxItems(values []string, n int) bool {
+	return len(values) <= n
+}
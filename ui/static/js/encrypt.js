@@ -0,0 +1,187 @@
+// Encrypted snippets are encrypted in the browser before
+// they are sent, and decrypted in the browser when they
+// are viewed, so the server only ever stores an opaque
+// blob. The key is kept in the fragment of the snippet's
+// link, the part after "#", which browsers never send to
+// the server.
+//
+// The key is a 256 bit AES-GCM key, encoded as base64url
+// without padding. The blob is the base64 encoding of a
+// random 12 byte nonce followed by the ciphertext, which
+// is what checkEncrypted() in cmd/web/handlers.go checks
+// for.
+(function () {
+	"use strict";
+
+	// The Web Crypto API is only available on pages
+	// served over HTTPS
+	if (!window.crypto || !window.crypto.subtle) {
+		return;
+	}
+
+	var subtle = window.crypto.subtle;
+	var algorithm = "AES-GCM";
+	var nonceSize = 12;
+
+	function toBase64(bytes) {
+		var binary = "";
+		for (var i = 0; i < bytes.length; i++) {
+			binary += String.fromCharCode(bytes[i]);
+		}
+		return btoa(binary);
+	}
+
+	function fromBase64(text) {
+		var binary = atob(text);
+		var bytes = new Uint8Array(binary.length);
+		for (var i = 0; i < binary.length; i++) {
+			bytes[i] = binary.charCodeAt(i);
+		}
+		return bytes;
+	}
+
+	function toBase64URL(bytes) {
+		return toBase64(bytes).replace(/\+/g, "-").replace(/\//g, "_").replace(/=+$/, "");
+	}
+
+	function fromBase64URL(text) {
+		text = text.replace(/-/g, "+").replace(/_/g, "/");
+		while (text.length % 4 !== 0) {
+			text += "=";
+		}
+		return fromBase64(text);
+	}
+
+	// keyFromLink resolves to the key in the page's
+	// fragment, or null if there isn't one.
+	function keyFromLink() {
+		var encoded = window.location.hash.slice(1);
+		if (encoded === "") {
+			return Promise.resolve(null);
+		}
+		return Promise.resolve().then(function () {
+			return subtle.importKey("raw", fromBase64URL(encoded), algorithm, true, ["encrypt", "decrypt"]);
+		});
+	}
+
+	function newKey() {
+		return subtle.generateKey({ name: algorithm, length: 256 }, true, ["encrypt", "decrypt"]);
+	}
+
+	function encrypt(key, text) {
+		var nonce = window.crypto.getRandomValues(new Uint8Array(nonceSize));
+		var plaintext = new TextEncoder().encode(text);
+
+		return subtle.encrypt({ name: algorithm, iv: nonce }, key, plaintext).then(function (ciphertext) {
+			var blob = new Uint8Array(nonceSize + ciphertext.byteLength);
+			blob.set(nonce);
+			blob.set(new Uint8Array(ciphertext), nonceSize);
+			return toBase64(blob);
+		});
+	}
+
+	function decrypt(key, blob) {
+		return Promise.resolve().then(function () {
+			var bytes = fromBase64(blob.trim());
+			var nonce = bytes.subarray(0, nonceSize);
+			return subtle.decrypt({ name: algorithm, iv: nonce }, key, bytes.subarray(nonceSize));
+		}).then(function (plaintext) {
+			return new TextDecoder().decode(plaintext);
+		});
+	}
+
+	// Show encrypted snippets. Without the right key the
+	// blob is replaced by a message saying why.
+	var blobs = document.querySelectorAll("pre[data-encrypted]");
+	for (var i = 0; i < blobs.length; i++) {
+		showSnippet(blobs[i]);
+	}
+
+	function showSnippet(pre) {
+		keyFromLink().then(function (key) {
+			if (key === null) {
+				throw new Error("The key is missing from the link");
+			}
+			return decrypt(key, pre.textContent);
+		}).then(function (text) {
+			pre.textContent = text;
+		}, function () {
+			pre.textContent = "This snippet can't be decrypted. Check the link includes everything after the \"#\".";
+			pre.classList.add("error");
+		});
+	}
+
+	// Links and forms marked data-keep-key, like the edit
+	// link and the unlock form, pass the key on to the
+	// page they open
+	var keepers = document.querySelectorAll("[data-keep-key]");
+	for (var j = 0; j < keepers.length; j++) {
+		if (keepers[j].tagName === "FORM") {
+			keepers[j].action = keepers[j].action.split("#")[0] + window.location.hash;
+		} else {
+			keepers[j].hash = window.location.hash;
+		}
+	}
+
+	// Forms marked data-encrypt encrypt their content
+	// field before they are sent, when their "encrypted"
+	// field is ticked or hidden
+	var forms = document.querySelectorAll("form[data-encrypt]");
+	for (var k = 0; k < forms.length; k++) {
+		setUpForm(forms[k]);
+	}
+
+	function setUpForm(form) {
+		var flag = form.elements["encrypted"];
+		var content = form.elements["content"];
+		if (!flag || !content) {
+			return;
+		}
+
+		function isEncrypted() {
+			return flag.type === "hidden" || flag.checked;
+		}
+
+		// When editing a snippet, or after the form was sent
+		// back with errors, the content is still encrypted.
+		// Decrypt it with the key in the link, and if that
+		// can't be done don't let the form be sent, which
+		// would encrypt the blob a second time.
+		var ready = Promise.resolve();
+		if (isEncrypted() && content.value !== "") {
+			ready = keyFromLink().then(function (key) {
+				return decrypt(key, content.value);
+			}).then(function (text) {
+				content.value = text;
+			}, function () {
+				content.readOnly = true;
+			});
+		}
+
+		form.addEventListener("submit", function (event) {
+			if (!isEncrypted() || content.readOnly || content.value.trim() === "") {
+				return;
+			}
+			event.preventDefault();
+
+			var key;
+			ready.then(keyFromLink).then(function (k) {
+				return k || newKey();
+			}).then(function (k) {
+				key = k;
+				return encrypt(key, content.value);
+			}).then(function (blob) {
+				content.value = blob;
+				return subtle.exportKey("raw", key);
+			}).then(function (raw) {
+				// The server redirects to the snippet once it is
+				// saved. Browsers keep the fragment of the form's
+				// action when following the redirect, so the key
+				// ends up in the snippet's link without being
+				// sent. submit() doesn't fire this handler again.
+				form.action = form.action.split("#")[0] + "#" + toBase64URL(new Uint8Array(raw));
+				form.submit();
+			});
+		});
+	}
+})();